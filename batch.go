@@ -0,0 +1,200 @@
+package main
+
+import (
+  "bufio"
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+  "sync"
+
+  "github.com/msarmie/canopus-mets-parser/metsparser"
+)
+
+// Summary of a batch run
+type batchReport struct {
+  Total     int           `json:"total"`
+  Succeeded int           `json:"succeeded"`
+  Failed    int           `json:"failed"`
+  Results   []batchResult `json:"results"`
+}
+
+// Outcome for one METS file in a batch
+type batchResult struct {
+  Mets   string `json:"mets"`
+  Output string `json:"output,omitempty"`
+  Error  string `json:"error,omitempty"`
+}
+
+// build one METS file into an output directory, returning the JSON path
+type buildFunc func(filePath string, target string) (string, error)
+
+// run build over every METS path with a pool of workers, never stopping on a
+// failure. Each METS is built in a staging directory of its own and moved to
+// target in input order, so of several METS describing one package the first
+// listed is written whichever worker finishes first.
+func runBatch(paths []string, target string, workers int, build buildFunc) batchReport {
+  if workers < 1 {
+    workers = 1
+  }
+  p := &publisher{target: target, results: make([]batchResult, len(paths)), finished: make([]bool, len(paths)), written: make(map[string]string)}
+  staging, err := ioutil.TempDir(target, ".canopus-batch-")
+  if err != nil {
+    for i, path := range paths {
+      p.results[i] = batchResult{Mets: path, Error: err.Error()}
+    }
+    return summarize(p.results)
+  }
+  defer os.RemoveAll(staging)
+
+  jobs := make(chan int)
+  var wg sync.WaitGroup
+  for w := 0; w < workers; w++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      for i := range jobs {
+        stage := filepath.Join(staging, strconv.Itoa(i))
+        err := os.Mkdir(stage, 0750)
+        if err != nil {
+          p.finish(i, batchResult{Mets: paths[i], Error: err.Error()})
+          continue
+        }
+        p.finish(i, buildOne(paths[i], stage, build))
+      }
+    }()
+  }
+  for i := range paths {
+    jobs <- i
+  }
+  close(jobs)
+  wg.Wait()
+  return summarize(p.results)
+}
+
+// count the outcomes of a batch
+func summarize(results []batchResult) batchReport {
+  report := batchReport{Total: len(results), Results: results}
+  for _, r := range results {
+    if r.Error == "" {
+      report.Succeeded++
+    } else {
+      report.Failed++
+    }
+  }
+  return report
+}
+
+// publisher moves staged manifests to the output directory in input order,
+// failing a METS whose manifest an earlier one of the run already wrote
+type publisher struct {
+  sync.Mutex
+  target   string
+  results  []batchResult
+  finished []bool
+  next     int               // first result not published yet
+  written  map[string]string // output path -> METS path
+}
+
+// record the result of the METS at index i, then publish the results up to
+// the first one still building
+func (p *publisher) finish(i int, result batchResult) {
+  p.Lock()
+  defer p.Unlock()
+  p.results[i] = result
+  p.finished[i] = true
+  for p.next < len(p.results) && p.finished[p.next] {
+    p.publish(&p.results[p.next])
+    p.next++
+  }
+}
+
+func (p *publisher) publish(r *batchResult) {
+  if r.Error != "" {
+    return
+  }
+  output := p.target + "/" + filepath.Base(r.Output)
+  other, ok := p.written[output]
+  if ok {
+    r.Output = ""
+    r.Error = fmt.Sprintf("%s: already written for %s, both describe the same package", output, other)
+    return
+  }
+  err := os.Rename(r.Output, output)
+  if err != nil {
+    r.Output = ""
+    r.Error = err.Error()
+    return
+  }
+  p.written[output] = r.Mets
+  r.Output = output
+}
+
+// build a single METS, turning a panic into a per-file error so the batch carries on
+func buildOne(filePath string, target string, build buildFunc) (result batchResult) {
+  result.Mets = filePath
+  defer func() {
+    if r := recover(); r != nil {
+      result.Output = ""
+      result.Error = fmt.Sprintf("panic: %v", r)
+    }
+  }()
+  output, err := build(filePath, target)
+  if err != nil {
+    result.Error = err.Error()
+    return result
+  }
+  result.Output = output
+  return result
+}
+
+// find Archivematica METS files (METS.<uuid>.xml) and packed AIPs under a directory
+func findMetsFiles(root string) ([]string, error) {
+  var paths []string
+  err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+    if err != nil {
+      return err
+    }
+    if !info.IsDir() && (metsparser.IsMetsFileName(info.Name()) || metsparser.IsPackedAIP(info.Name())) {
+      paths = append(paths, path)
+    }
+    return nil
+  })
+  return paths, err
+}
+
+// read METS paths from a list file, one per line, skipping blanks and # comments
+func readListFile(listPath string) ([]string, error) {
+  file, err := os.Open(listPath)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+
+  var paths []string
+  scanner := bufio.NewScanner(file)
+  for scanner.Scan() {
+    line := strings.TrimSpace(scanner.Text())
+    if line == "" || strings.HasPrefix(line, "#") {
+      continue
+    }
+    paths = append(paths, line)
+  }
+  return paths, scanner.Err()
+}
+
+// write a report as JSON, to stdout when no path is given
+func writeReport(reportPath string, report interface{}) error {
+  output, err := json.MarshalIndent(report, "", "  ")
+  if err != nil {
+    return err
+  }
+  if reportPath == "" {
+    fmt.Println(string(output))
+    return nil
+  }
+  return ioutil.WriteFile(reportPath, output, 0750)
+}
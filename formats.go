@@ -0,0 +1,160 @@
+package main
+
+import (
+  "bytes"
+  "encoding/csv"
+  "flag"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "log"
+  "os"
+  "strconv"
+  "strings"
+
+  "github.com/msarmie/canopus-mets-parser/metsparser"
+)
+
+// formats subcommand: format profile of many packages, from METS files,
+// packed AIPs or manifests, as JSON, CSV or Markdown
+func runFormats(args []string) int {
+  flags := flag.NewFlagSet("formats", flag.ExitOnError)
+  batchDirUserInput := flags.String("batch", "", "Also read every METS.<uuid>.xml and packed AIP found under a directory")
+  outputFormatUserInput := flags.String("format", "json", "Report format: json, csv or markdown")
+  reportFileUserInput := flags.String("report", "", "Write the report to a file instead of stdout")
+  manifestFlags := addManifestFlags(flags)
+  flags.Parse(args)
+
+  opts, err := manifestFlags.options()
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+
+  paths := flags.Args()
+  if *batchDirUserInput != "" {
+    found, err := findMetsFiles(*batchDirUserInput)
+    if err != nil {
+      log.Print(err)
+      return 2
+    }
+    paths = append(paths, found...)
+  }
+
+  // profiled package by package, the report only keeps the counts and the
+  // unidentified files
+  report := metsparser.FormatReport{Packages: []metsparser.PackageFormats{}, Formats: []metsparser.FormatCount{}, Unidentified: []metsparser.UnidentifiedFile{}}
+  for _, path := range paths {
+    manifestObject, err := loadManifest(path, opts)
+    if err != nil {
+      log.Printf("%s: %v", path, err)
+      return 2
+    }
+    report.Add(manifestObject)
+  }
+
+  switch *outputFormatUserInput {
+  case "json":
+    err = writeReport(*reportFileUserInput, &report)
+  case "csv", "markdown", "md":
+    var output bytes.Buffer
+    if *outputFormatUserInput == "csv" {
+      err = writeFormatsCSV(&output, report)
+    } else {
+      writeFormatsMarkdown(&output, report)
+    }
+    if err == nil {
+      err = writeText(*reportFileUserInput, output.Bytes())
+    }
+  default:
+    log.Printf("ERROR : UNKNOWN REPORT FORMAT %q", *outputFormatUserInput)
+    return 2
+  }
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+  return 0
+}
+
+// one row per format of each package and across packages (empty package),
+// then one row per unidentified file
+func writeFormatsCSV(w io.Writer, report metsparser.FormatReport) error {
+  out := csv.NewWriter(w)
+  out.Write([]string{"kind", "package", "puid", "format", "version", "mime", "files", "bytes", "filename"})
+  row := func(pkg string, f metsparser.FormatCount) {
+    out.Write([]string{"format", pkg, f.Puid, f.Format, f.Version, f.Mime, strconv.FormatInt(f.Files, 10), strconv.FormatInt(f.Bytes, 10), ""})
+  }
+  for _, p := range report.Packages {
+    for _, f := range p.Formats {
+      row(p.Package, f)
+    }
+  }
+  for _, f := range report.Formats {
+    row("", f)
+  }
+  for _, u := range report.Unidentified {
+    out.Write([]string{"unidentified", u.Package, "", "", "", "", "1", strconv.FormatInt(u.Bytes, 10), u.FileName})
+  }
+  out.Flush()
+  return out.Error()
+}
+
+// a table per package, one across packages and the unidentified files
+func writeFormatsMarkdown(w io.Writer, report metsparser.FormatReport) {
+  table := func(formats []metsparser.FormatCount, packages bool) {
+    if packages {
+      fmt.Fprintln(w, "| PUID | Format | Version | MIME | Files | Bytes | Packages |")
+      fmt.Fprintln(w, "|---|---|---|---|---:|---:|---:|")
+    } else {
+      fmt.Fprintln(w, "| PUID | Format | Version | MIME | Files | Bytes |")
+      fmt.Fprintln(w, "|---|---|---|---|---:|---:|")
+    }
+    for _, f := range formats {
+      cells := []string{cell(f.Puid), cell(f.Format), cell(f.Version), cell(f.Mime), strconv.FormatInt(f.Files, 10), strconv.FormatInt(f.Bytes, 10)}
+      if packages {
+        cells = append(cells, strconv.FormatInt(f.Packages, 10))
+      }
+      fmt.Fprintln(w, "| "+strings.Join(cells, " | ")+" |")
+    }
+    fmt.Fprintln(w)
+  }
+
+  fmt.Fprintln(w, "# Format profile")
+  fmt.Fprintln(w)
+  for _, p := range report.Packages {
+    fmt.Fprintf(w, "## %s\n\n", p.Package)
+    table(p.Formats, false)
+    if p.Unidentified > 0 {
+      fmt.Fprintf(w, "%d unidentified files.\n\n", p.Unidentified)
+    }
+  }
+  if len(report.Packages) > 1 {
+    fmt.Fprintln(w, "## All packages")
+    fmt.Fprintln(w)
+    table(report.Formats, true)
+  }
+  if len(report.Unidentified) > 0 {
+    fmt.Fprintln(w, "## Unidentified files")
+    fmt.Fprintln(w)
+    fmt.Fprintln(w, "| Package | File | Use | Bytes |")
+    fmt.Fprintln(w, "|---|---|---|---:|")
+    for _, u := range report.Unidentified {
+      fmt.Fprintf(w, "| %s | %s | %s | %d |\n", cell(u.Package), cell(u.FileName), cell(u.Use), u.Bytes)
+    }
+  }
+}
+
+// escape a Markdown table cell
+func cell(value string) string {
+  return strings.Replace(value, "|", "\\|", -1)
+}
+
+// print a text report, or write it to a file
+func writeText(reportPath string, output []byte) error {
+  if reportPath == "" {
+    _, err := os.Stdout.Write(output)
+    return err
+  }
+  return ioutil.WriteFile(reportPath, output, 0750)
+}
@@ -0,0 +1,124 @@
+package metsparser
+
+import (
+  "archive/tar"
+  "archive/zip"
+  "compress/gzip"
+  "io"
+  "os"
+  "path"
+  "strings"
+)
+
+// IsMetsFileName reports whether name follows Archivematica's METS.<uuid>.xml convention
+func IsMetsFileName(name string) bool {
+  return strings.HasPrefix(name, "METS.") && strings.HasSuffix(name, ".xml") && name != "METS.xml"
+}
+
+// IsPackedAIP reports whether filePath has an archive extension OpenAIP supports
+func IsPackedAIP(filePath string) bool {
+  return packageFormat(filePath) != ""
+}
+
+// OpenAIP opens the METS file inside a packed AIP (.tar, .tar.gz, .tgz or .zip)
+// without extracting the rest of the payload
+func OpenAIP(filePath string) (io.ReadCloser, error) {
+  switch packageFormat(filePath) {
+  case "zip":
+    return openZipMets(filePath)
+  case "tar", "tar.gz":
+    return openTarMets(filePath)
+  }
+  return nil, ErrUnsupportedPackage
+}
+
+// return the archive format from the file extension
+func packageFormat(filePath string) string {
+  name := strings.ToLower(filePath)
+  switch {
+  case strings.HasSuffix(name, ".zip"):
+    return "zip"
+  case strings.HasSuffix(name, ".tar"):
+    return "tar"
+  case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+    return "tar.gz"
+  }
+  return ""
+}
+
+// true for <aip>/data/METS.<uuid>.xml, the AIP METS rather than a transfer METS in objects
+func isPackedMetsPath(name string) bool {
+  dir, file := path.Split(strings.TrimPrefix(name, "./"))
+  return IsMetsFileName(file) && path.Base(path.Clean(dir)) == "data" && strings.Count(dir, "/") <= 2
+}
+
+func openZipMets(filePath string) (io.ReadCloser, error) {
+  archive, err := zip.OpenReader(filePath)
+  if err != nil {
+    return nil, err
+  }
+  for _, f := range archive.File {
+    if isPackedMetsPath(f.Name) {
+      r, err := f.Open()
+      if err != nil {
+        archive.Close()
+        return nil, err
+      }
+      return &packedReader{Reader: r, closers: []io.Closer{r, archive}}, nil
+    }
+  }
+  archive.Close()
+  return nil, ErrMetsNotFound
+}
+
+func openTarMets(filePath string) (io.ReadCloser, error) {
+  file, err := os.Open(filePath)
+  if err != nil {
+    return nil, err
+  }
+  closers := []io.Closer{file}
+  var r io.Reader = file
+  if packageFormat(filePath) == "tar.gz" {
+    gz, err := gzip.NewReader(file)
+    if err != nil {
+      file.Close()
+      return nil, err
+    }
+    closers = append([]io.Closer{gz}, closers...)
+    r = gz
+  }
+
+  archive := tar.NewReader(r)
+  for {
+    header, err := archive.Next()
+    if err == io.EOF {
+      err = ErrMetsNotFound
+    }
+    if err != nil {
+      for _, c := range closers {
+        c.Close()
+      }
+      return nil, err
+    }
+    if header.FileInfo().Mode().IsRegular() && isPackedMetsPath(header.Name) {
+      return &packedReader{Reader: archive, closers: closers}, nil
+    }
+  }
+}
+
+// reader over a file inside an archive, closing the archive with it
+type packedReader struct {
+  io.Reader
+  closers []io.Closer
+}
+
+func (p *packedReader) Close() error {
+  var err error
+  for _, c := range p.closers {
+    cerr := c.Close()
+    if err == nil {
+      err = cerr
+    }
+  }
+  return err
+}
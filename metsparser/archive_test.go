@@ -0,0 +1,142 @@
+package metsparser
+
+import (
+  "archive/tar"
+  "archive/zip"
+  "compress/gzip"
+  "errors"
+  "io"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+// an archive entry
+type entry struct {
+  name    string
+  content string
+}
+
+func writeTar(w io.Writer, entries []entry) error {
+  archive := tar.NewWriter(w)
+  for _, e := range entries {
+    err := archive.WriteHeader(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg})
+    if err != nil {
+      return err
+    }
+    _, err = archive.Write([]byte(e.content))
+    if err != nil {
+      return err
+    }
+  }
+  return archive.Close()
+}
+
+// write entries to an archive of the format the file name gives
+func writeArchive(t *testing.T, filePath string, entries []entry) {
+  file, err := os.Create(filePath)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer file.Close()
+  switch packageFormat(filePath) {
+  case "zip":
+    archive := zip.NewWriter(file)
+    for _, e := range entries {
+      w, err := archive.Create(e.name)
+      if err == nil {
+        _, err = w.Write([]byte(e.content))
+      }
+      if err != nil {
+        t.Fatal(err)
+      }
+    }
+    err = archive.Close()
+  case "tar":
+    err = writeTar(file, entries)
+  case "tar.gz":
+    gz := gzip.NewWriter(file)
+    err = writeTar(gz, entries)
+    if err == nil {
+      err = gz.Close()
+    }
+  }
+  if err != nil {
+    t.Fatal(err)
+  }
+}
+
+func TestOpenAIP(t *testing.T) {
+  dir, err := ioutil.TempDir("", "aip")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  aip := []entry{
+    {"pkg-abc/bagit.txt", "BagIt-Version: 0.97"},
+    // the transfer METS kept with the submission documentation comes first
+    {"pkg-abc/data/objects/submissionDocumentation/transfer-abc/METS.xml", "transfer"},
+    {"pkg-abc/data/objects/METS.0b1a.xml", "nested"},
+    {"pkg-abc/data/METS.abc.xml", "aip"},
+  }
+  noMets := []entry{
+    {"pkg-abc/bagit.txt", "BagIt-Version: 0.97"},
+    {"pkg-abc/data/objects/METS.0b1a.xml", "nested"},
+  }
+  for _, name := range []string{"aip.zip", "aip.tar", "aip.tar.gz", "aip.tgz", "AIP.ZIP"} {
+    t.Run(name, func(t *testing.T) {
+      filePath := filepath.Join(dir, name)
+      writeArchive(t, filePath, aip)
+      r, err := OpenAIP(filePath)
+      if err != nil {
+        t.Fatal(err)
+      }
+      content, err := ioutil.ReadAll(r)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if string(content) != "aip" {
+        t.Errorf("read %q, want the AIP METS", content)
+      }
+      err = r.Close()
+      if err != nil {
+        t.Error(err)
+      }
+
+      filePath = filepath.Join(dir, "no-mets-"+name)
+      writeArchive(t, filePath, noMets)
+      _, err = OpenAIP(filePath)
+      if !errors.Is(err, ErrMetsNotFound) {
+        t.Errorf("got %v, want ErrMetsNotFound", err)
+      }
+    })
+  }
+
+  _, err = OpenAIP(filepath.Join(dir, "aip.7z"))
+  if !errors.Is(err, ErrUnsupportedPackage) {
+    t.Errorf("got %v, want ErrUnsupportedPackage", err)
+  }
+}
+
+func TestIsPackedMetsPath(t *testing.T) {
+  tests := []struct {
+    name string
+    want bool
+  }{
+    {"pkg-abc/data/METS.abc.xml", true},
+    {"./pkg-abc/data/METS.abc.xml", true},
+    {"data/METS.abc.xml", true},
+    {"pkg-abc/data/METS.xml", false},
+    {"pkg-abc/data/objects/METS.abc.xml", false},
+    {"pkg-abc/data/objects/data/METS.abc.xml", false},
+    {"pkg-abc/METS.abc.xml", false},
+    {"pkg-abc/data/METS.abc.xml.bak", false},
+  }
+  for _, tt := range tests {
+    if got := isPackedMetsPath(tt.name); got != tt.want {
+      t.Errorf("%q: got %v, want %v", tt.name, got, tt.want)
+    }
+  }
+}
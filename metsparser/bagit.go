@@ -0,0 +1,120 @@
+package metsparser
+
+import (
+  "fmt"
+  "math"
+  "strconv"
+  "strings"
+)
+
+// compare the Payload-Oxum and Bag-Size declared by the transfers with the
+// parsed payload, returning a warning for each discrepancy
+func checkBagTotals(sources []SourceMD, totalSize int64, fileCount int64) []string {
+  var warnings []string
+  var oxumSize, oxumCount int64
+  oxumFound := false
+  for _, s := range sources {
+    if s.Payload != "" {
+      size, count, err := parseOxum(s.Payload)
+      if err != nil {
+        warnings = append(warnings, fmt.Sprintf("unrecognized Payload-Oxum %q", s.Payload))
+      } else {
+        oxumSize += size
+        oxumCount += count
+        oxumFound = true
+      }
+    }
+    if s.BagCount != "" {
+      bag, total, ok := parseBagCount(s.BagCount)
+      if ok && total > 1 {
+        warnings = append(warnings, fmt.Sprintf("transfer is bag %d of %d, totals only cover part of the bag group", bag, total))
+      }
+    }
+  }
+  if oxumFound && (oxumSize != totalSize || oxumCount != fileCount) {
+    warnings = append(warnings, fmt.Sprintf("Payload-Oxum declares %d bytes in %d files, parsed %d bytes in %d files", oxumSize, oxumCount, totalSize, fileCount))
+  }
+
+  // Bag-Size is approximate, only one transfer can be compared
+  if len(sources) == 1 && sources[0].BagSize != "" {
+    bagSize := sources[0].BagSize
+    low, high, ok := bagSizeRange(bagSize)
+    if !ok {
+      warnings = append(warnings, fmt.Sprintf("unrecognized Bag-Size %q", bagSize))
+    } else if float64(totalSize) < low || float64(totalSize) > high {
+      warnings = append(warnings, fmt.Sprintf("Bag-Size declares %s, parsed %d bytes", bagSize, totalSize))
+    }
+  }
+  return warnings
+}
+
+// parse a Payload-Oxum, "<octet count>.<stream count>"
+func parseOxum(oxum string) (int64, int64, error) {
+  parts := strings.Split(strings.TrimSpace(oxum), ".")
+  if len(parts) != 2 {
+    return 0, 0, fmt.Errorf("Payload-Oxum %q is not <bytes>.<count>", oxum)
+  }
+  size, err := strconv.ParseInt(parts[0], 10, 64)
+  if err != nil {
+    return 0, 0, err
+  }
+  count, err := strconv.ParseInt(parts[1], 10, 64)
+  if err != nil {
+    return 0, 0, err
+  }
+  return size, count, nil
+}
+
+// parse a Bag-Count, "<bag> of <total>", total may be "?"
+func parseBagCount(bagCount string) (int64, int64, bool) {
+  parts := strings.Fields(bagCount)
+  if len(parts) != 3 || parts[1] != "of" {
+    return 0, 0, false
+  }
+  bag, err := strconv.ParseInt(parts[0], 10, 64)
+  if err != nil {
+    return 0, 0, false
+  }
+  total, err := strconv.ParseInt(parts[2], 10, 64)
+  if err != nil {
+    return 0, 0, false
+  }
+  return bag, total, true
+}
+
+// range of byte counts a human readable Bag-Size such as "2.5 GB" can stand
+// for, allowing for rounding and either decimal or binary units
+func bagSizeRange(bagSize string) (float64, float64, bool) {
+  fields := strings.Fields(bagSize)
+  if len(fields) == 0 || len(fields) > 2 {
+    return 0, 0, false
+  }
+  number, unit := fields[0], "B"
+  if len(fields) == 2 {
+    unit = fields[1]
+  } else {
+    i := strings.IndexFunc(number, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+    if i > 0 {
+      number, unit = number[:i], number[i:]
+    }
+  }
+  value, err := strconv.ParseFloat(number, 64)
+  if err != nil {
+    return 0, 0, false
+  }
+  exponent := map[string]float64{"B": 0, "KB": 1, "MB": 2, "GB": 3, "TB": 4, "PB": 5,
+    "KIB": 1, "MIB": 2, "GIB": 3, "TIB": 4, "PIB": 5, "BYTES": 0}
+  e, ok := exponent[strings.ToUpper(unit)]
+  if !ok {
+    return 0, 0, false
+  }
+  // rounding of the last printed digit
+  decimals := 0
+  if i := strings.Index(number, "."); i >= 0 {
+    decimals = len(number) - i - 1
+  }
+  step := 0.5 * math.Pow(10, -float64(decimals))
+  low := (value - step) * math.Pow(1000, e)
+  high := (value + step) * math.Pow(1024, e)
+  return low, high, true
+}
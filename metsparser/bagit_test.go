@@ -0,0 +1,115 @@
+package metsparser
+
+import (
+  "bytes"
+  "io/ioutil"
+  "reflect"
+  "testing"
+)
+
+func TestParseOxum(t *testing.T) {
+  tests := []struct {
+    oxum  string
+    size  int64
+    count int64
+    ok    bool
+  }{
+    {"1024.3", 1024, 3, true},
+    {" 0.0 ", 0, 0, true},
+    {"1024", 0, 0, false},
+    {"1024.3.1", 0, 0, false},
+    {"1 KB.3", 0, 0, false},
+    {"1024.x", 0, 0, false},
+    {"", 0, 0, false},
+  }
+  for _, tt := range tests {
+    size, count, err := parseOxum(tt.oxum)
+    if (err == nil) != tt.ok || size != tt.size || count != tt.count {
+      t.Errorf("%q: got %d %d %v, want %d %d ok=%v", tt.oxum, size, count, err, tt.size, tt.count, tt.ok)
+    }
+  }
+}
+
+func TestBagSizeRange(t *testing.T) {
+  tests := []struct {
+    bagSize string
+    low     float64
+    high    float64
+    ok      bool
+  }{
+    {"512", 511.5, 512.5, true},
+    {"512 bytes", 511.5, 512.5, true},
+    {"1 KB", 500, 1536, true},
+    {"1KB", 500, 1536, true},
+    {"1 kib", 500, 1536, true},
+    {"2.5 MB", 2.45e6, 2.55 * 1024 * 1024, true},
+    {"3 GB", 2.5e9, 3.5 * 1024 * 1024 * 1024, true},
+    {"", 0, 0, false},
+    {"1 XB", 0, 0, false},
+    {"KB", 0, 0, false},
+    {"1 2 KB", 0, 0, false},
+  }
+  for _, tt := range tests {
+    low, high, ok := bagSizeRange(tt.bagSize)
+    if ok != tt.ok || !closeTo(low, tt.low) || !closeTo(high, tt.high) {
+      t.Errorf("%q: got %v %v %v, want %v %v %v", tt.bagSize, low, high, ok, tt.low, tt.high, tt.ok)
+    }
+  }
+}
+
+func closeTo(a, b float64) bool {
+  return a-b < 1e-6*b+1e-9 && b-a < 1e-6*b+1e-9
+}
+
+func TestCheckBagTotals(t *testing.T) {
+  tests := []struct {
+    name     string
+    sources  []SourceMD
+    warnings []string
+  }{
+    {"no bag-info", nil, nil},
+    {"matching", []SourceMD{{Payload: "1000.2", BagSize: "1 KB", BagCount: "1 of 1"}}, nil},
+    {"transfers summed", []SourceMD{{Payload: "600.1"}, {Payload: "400.1"}}, nil},
+    {"payload differs", []SourceMD{{Payload: "999.2"}},
+      []string{"Payload-Oxum declares 999 bytes in 2 files, parsed 1000 bytes in 2 files"}},
+    {"unrecognized payload", []SourceMD{{Payload: "1000"}},
+      []string{`unrecognized Payload-Oxum "1000"`}},
+    {"bag size differs", []SourceMD{{BagSize: "5 KB"}},
+      []string{"Bag-Size declares 5 KB, parsed 1000 bytes"}},
+    {"unrecognized bag size", []SourceMD{{BagSize: "big"}},
+      []string{`unrecognized Bag-Size "big"`}},
+    {"bag size of several transfers", []SourceMD{{BagSize: "5 KB"}, {BagSize: "5 KB"}}, nil},
+    {"bag group", []SourceMD{{Payload: "1000.2", BagCount: "2 of 3"}},
+      []string{"transfer is bag 2 of 3, totals only cover part of the bag group"}},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      warnings := checkBagTotals(tt.sources, 1000, 2)
+      if !reflect.DeepEqual(warnings, tt.warnings) {
+        t.Errorf("got %q, want %q", warnings, tt.warnings)
+      }
+    })
+  }
+}
+
+// a transfer's bag-info repeated in the METS is counted once
+func TestRepeatedBagInfo(t *testing.T) {
+  data, err := ioutil.ReadFile("testdata/METS.repeated-bag-info.xml")
+  if err != nil {
+    t.Fatal(err)
+  }
+  mets, err := Parse(bytes.NewReader(data))
+  if err != nil {
+    t.Fatal(err)
+  }
+  m, err := BuildManifest(mets, Options{})
+  if err != nil {
+    t.Fatal(err)
+  }
+  if m.DepositorName != "Jane Doe" {
+    t.Errorf("depositor %q, want Jane Doe", m.DepositorName)
+  }
+  for _, w := range m.Warnings {
+    t.Errorf("unexpected warning %q", w)
+  }
+}
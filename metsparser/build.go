@@ -0,0 +1,482 @@
+package metsparser
+
+import (
+  "strconv"
+  "strings"
+  "time"
+)
+
+// Options controls how a manifest is built from a parsed METS
+type Options struct {
+  // bag-info fields mapped into the manifest, DefaultTransferMapping when nil
+  TransferMapping *TransferMapping
+  // date access restrictions are evaluated at, today when zero
+  RightsDate time.Time
+  // list every version of each file's dublincore, not only the current one
+  DescriptiveHistory bool
+  // fileGrp USE values to list, every group when empty
+  IncludeUse []string
+  // fileGrp USE values left out of the manifest
+  ExcludeUse []string
+  // copy each file's agents into it instead of referencing the package agents
+  EmbedAgents bool
+  // event types whose failure makes building the manifest fail, none when empty
+  CriticalEvents []string
+  // local format registry files are assessed against, no assessment when nil
+  FormatPolicy *FormatPolicy
+}
+
+// BuildManifest assembles the Canopus manifest for a parsed METS
+func BuildManifest(mets *Mets, opts Options) (*ObjectMetsManifest, error) {
+  if mets.DescriptiveSec == nil {
+    return nil, ErrMissingDmdSec
+  }
+
+  // get descriptive metadata
+  dublincore := getDublinCore(mets)
+  structmap := getFileIdDdmdIdStructMap(mets.StructMap)
+  filemap := make(map[string]FileMapped)
+  for _, value := range getAmdIdByFileIdFileSec(mets.FileSec, structmap) {
+    filemap[value.Admid] = value
+  }
+  b, err := newBuilder(opts, nil)
+  if err != nil {
+    return nil, err
+  }
+  b.objects = structmap["objects"]
+  b.fileMapped = func(admid string) (FileMapped, bool) {
+    value, ok := filemap[admid]
+    return value, ok
+  }
+  b.packageName = getParentPackage(mets.StructMap)
+  b.createDate = mets.Header.CreateDate
+  b.dublincore = func(id string) (DescriptiveSec, bool) {
+    dc, ok := dublincore[id]
+    return dc, ok
+  }
+  b.relations = newRelations(nil)
+  for _, a := range mets.AdminSec {
+    b.relations.add(linksOf(a))
+  }
+
+  var files []FilesMets
+  // one adminsec for each file
+  for _, a := range mets.AdminSec {
+    file, err := b.file(a)
+    if err != nil {
+      return nil, err
+    }
+    if file != nil {
+      files = append(files, *file)
+    }
+  }
+
+  manifestObject, err := b.manifest()
+  if err != nil {
+    return nil, err
+  }
+  manifestObject.Rights = b.rights.statements()
+  manifestObject.SfErrors = b.status.formatErrors()
+  manifestObject.Quality = b.quality.result()
+  for _, file := range files {
+    manifestObject.Manifest.Add(file)
+  }
+  return manifestObject, nil
+}
+
+// builder resolves files one amdSec at a time and accumulates package level
+// metadata. What grows with the files is kept in tables and lists of its store.
+type builder struct {
+  opts         Options
+  objects      []string // DMDIDs of the objects directory
+  fileMapped   func(admid string) (FileMapped, bool)
+  dublincore   func(id string) (DescriptiveSec, bool)
+  packageName  string
+  createDate   string
+  fileCount    int64
+  totalSize    int64
+  originals    int64
+  originalSize int64
+  siegfried    *Events
+  transfer     *transferMetadata
+  rights       packageRights
+  rightsDate   time.Time
+  relations    *relations
+  agents       packageAgents
+  status       packageStatus
+  tools        toolInventory
+  risks        *riskSummary
+  quality      qualitySummary
+}
+
+func newBuilder(opts Options, s *store) (*builder, error) {
+  transfer, err := newTransferMetadata(opts.TransferMapping)
+  if err != nil {
+    return nil, err
+  }
+  b := &builder{opts: opts, transfer: transfer}
+  b.rightsDate = opts.RightsDate
+  if b.rightsDate.IsZero() {
+    b.rightsDate = time.Now()
+  }
+  if opts.FormatPolicy != nil {
+    b.risks = &riskSummary{policy: opts.FormatPolicy}
+  }
+  b.rights.seen, b.rights.rights = s.table(), s.list()
+  b.status.sfErrors = s.list()
+  b.quality.flagged = s.list()
+  return b, nil
+}
+
+// return the file described by an amdSec, nil if the amdSec has no techMD or
+// its fileGrp isn't listed
+func (b *builder) file(a AdminSec) (*FilesMets, error) {
+  // transfer bag-info, recorded in its own amdSec
+  err := b.transfer.add(a.SourceMD)
+  if err != nil {
+    return nil, &FileError{Admid: a.ID, Field: "sourceMD", Err: err}
+  }
+  rights := getPremisRights(a)
+  b.rights.add(rights, b.rightsDate)
+  if (a.TechnicalMD.ID == "") {
+    return nil, nil
+  }
+  file := FilesMets{}
+  t := a.TechnicalMD
+  file.Md5 =  t.PremisObject.Fits.Md5
+  if t.PremisObject.Hashtype == "sha256" {
+    file.Sha256 =  t.PremisObject.Hashvalue
+  }
+  if (t.PremisObject.Bytes == "") {
+    return nil, &FileError{Admid: a.ID, Field: "size", Err: ErrEmptyBytes}
+  }
+  byte, err := strconv.Atoi(t.PremisObject.Bytes)
+  if err != nil {
+    return nil, &FileError{Admid: a.ID, Field: "size", Err: err}
+  }
+  file.FileSize = int64(byte)
+
+  // the bag payload counts originals whether they are listed or not
+  value, ok := b.fileMapped(a.ID)
+  if ok && value.Use == "original" {
+    b.originals++
+    b.originalSize += file.FileSize
+  }
+  if !b.opts.listsUse(value.Use) {
+    return nil, nil
+  }
+  file.Use = value.Use
+  if len(t.PremisObject.Uuid) > 0 {
+    file.Uuid = t.PremisObject.Uuid[0]
+  }
+  b.totalSize += file.FileSize
+  file.Modified = t.PremisObject.ModifiedDate // TODO
+
+  // PREMIS:EVENT and AGENTS
+  events, agents := getPremisEvents(a)
+
+  // file identification matches, PRONOM then FITS
+  file.Matches = fileMatches(t.PremisObject, events)
+  file.FormatConflict, file.MimeConflict = identificationConflicts(file.Matches, t.PremisObject.Fits)
+  if b.risks != nil {
+    risk := b.opts.FormatPolicy.assess(file.Matches[0])
+    file.FormatRisk = &risk
+    b.risks.add(risk)
+  }
+  if b.siegfried == nil {
+    b.siegfried = findSiegfriedEvent(events)
+  }
+
+  // DublinCore metadata
+  descriptivemd := DescriptiveMD{}
+  if ok {
+    file.FileName = value.Name
+    var history []DescriptiveMDVersion
+    descriptivemd, history = b.currentDublinCore(value.Dmdid) // [dmdSec_2, dmdSec_3]
+    if b.opts.DescriptiveHistory {
+      file.DescriptiveMDHistory = history
+    }
+  }
+  ids := b.agents.add(agents)
+  if b.opts.EmbedAgents {
+    descriptivemd.Events = events
+    descriptivemd.Agents = agents
+  } else {
+    descriptivemd.Events = b.agents.link(events)
+    descriptivemd.AgentIds = ids
+  }
+  file.DescriptiveMD = descriptivemd
+
+  // failed and warning event outcomes
+  setEventErrors(&file, events)
+  b.status.add(file.FileName, file.EventErrors, b.opts.CriticalEvents)
+
+  // suspicious content
+  name := file.FileName
+  if name == "" {
+    name = t.PremisObject.ObjectName
+  }
+  file.QualityWarnings = qualityWarnings(&file, name, t.PremisObject.Fits, b.opts.FormatPolicy)
+  b.quality.add(name, file.QualityWarnings)
+  file.Validation = validationResults(events)
+  b.tools.add(b.fileCount+1, events, t.PremisObject.Fits)
+
+  // PREMIS derivation relationships
+  file.DerivedFiles, file.SourceFiles = b.relatedFiles(t.PremisObject.Relationships)
+
+  // PREMIS:RIGHTS
+  file.Rights = rights
+  file.AccessRestricted, file.EmbargoEndDate = accessRestriction(rights, b.rightsDate)
+
+  b.fileCount++
+  return &file, nil
+}
+
+// return the package level manifest, without its files, the package rights,
+// the format identification problems and the files with quality warnings
+func (b *builder) manifest() (*ObjectMetsManifest, error) {
+  manifestObject := ObjectMetsManifest{}
+
+  // objects directory (transfer level) metadata
+  transferLevelDc, _ := b.currentDublinCore(b.objects)
+  manifestObject.Title = transferLevelDc.Title
+  if manifestObject.Title == "" {
+    manifestObject.Title = b.packageName
+  }
+  manifestObject.CollectionCall = transferLevelDc.Identifier
+  manifestObject.Description = transferLevelDc.Description
+  manifestObject.BaggingDate = b.createDate
+  manifestObject.FileCount = b.fileCount
+  manifestObject.TotalSize = b.totalSize
+  b.transfer.apply(&manifestObject)
+  manifestObject.AccessRestricted, manifestObject.EmbargoEndDate = b.rights.access.result()
+  manifestObject.Agents = b.agents.agents
+  manifestObject.Tools = b.tools.list()
+  if b.risks != nil {
+    manifestObject.FormatRisk = b.risks.result()
+  }
+  manifestObject.Quality = b.quality.counts()
+  err := b.status.applyStatus(&manifestObject)
+  if err != nil {
+    return nil, err
+  }
+
+  manifest := ManifestMets{}
+  e := b.siegfried
+  if e != nil {
+    manifest.Siegfried = e.Tool.Version
+    manifest.Scandate = e.DateTime
+  }
+  identifier := Identifiers{}
+  var identifiers []Identifiers
+  identifiers = append(identifiers, identifier)
+  manifest.Identifiers = identifiers
+  manifestObject.Manifest = manifest
+  manifestObject.StorageLocation = b.packageName
+  manifestObject.SchemaVersion = SchemaVersion
+
+  // the bag payload is the transfer as received, compare it with the originals
+  payloadSize, payloadCount := b.originalSize, b.originals
+  if payloadCount == 0 {
+    payloadSize, payloadCount = b.totalSize, b.fileCount
+  }
+  manifestObject.Warnings = append(manifestObject.Warnings, checkBagTotals(b.transfer.sources, payloadSize, payloadCount)...)
+
+  return &manifestObject, nil
+}
+
+// return map of dublincore dmdSecs identified by dmd ID
+func getDublinCore(mets *Mets) (map[string]DescriptiveSec){
+  dublincore := make(map[string]DescriptiveSec)
+  for _, desc := range mets.DescriptiveSec {
+    if desc.Dmd.Mdtype == "DC" {
+      dublincore[desc.ID] = desc
+    }
+  }
+  return dublincore
+}
+
+// pick the current dublincore among the versions an object's DMDID lists after
+// metadata reingests, see currentVersion. Returns no metadata if the current
+// version is deleted.
+func (b *builder) currentDublinCore(ids []string) (DescriptiveMD, []DescriptiveMDVersion) {
+  var versions []DescriptiveSec
+  var history []DescriptiveMDVersion
+  for _, id := range ids {
+    desc, ok := b.dublincore(id)
+    if !ok {
+      continue
+    }
+    dc := desc.Dmd.DublinCoreMD
+    dc.Language = strings.Join(dc.LanguageArr, ",")
+    dc.Subject = strings.Join(dc.SubjectArr, ",")
+    desc.Dmd.DublinCoreMD = dc
+    history = append(history, DescriptiveMDVersion{ID: desc.ID, Status: desc.Status, Created: desc.Created, DescriptiveMD: dc})
+    versions = append(versions, desc)
+  }
+
+  current := currentVersion(versions)
+  if current == nil || strings.EqualFold(current.Status, "deleted") {
+    return DescriptiveMD{}, history
+  }
+  return current.Dmd.DublinCoreMD, history
+}
+
+// the current one of several dmdSec versions, nil if there are none. A reingest
+// STATUS (updated, deleted) outranks original, which outranks a superseded one;
+// between equal statuses the later CREATED wins and a dated version beats an
+// undated one, then the last listed wins.
+func currentVersion(versions []DescriptiveSec) *DescriptiveSec {
+  var current *DescriptiveSec
+  for i := range versions {
+    if current == nil || replaces(versions[i], *current) {
+      current = &versions[i]
+    }
+  }
+  return current
+}
+
+// whether desc, listed after current, is the newer version
+func replaces(desc, current DescriptiveSec) bool {
+  rank, currentRank := statusRank(desc.Status), statusRank(current.Status)
+  if rank != currentRank {
+    return rank > currentRank
+  }
+  created, err := ParseEventDate(strings.TrimSpace(desc.Created))
+  currentCreated, currentErr := ParseEventDate(strings.TrimSpace(current.Created))
+  switch {
+  case err == nil && currentErr == nil && !created.Equal(currentCreated):
+    return created.After(currentCreated)
+  case err != nil && currentErr == nil:
+    return false
+  }
+  return true
+}
+
+// order dmdSec STATUS values by how recent a version they mark
+func statusRank(status string) int {
+  status = strings.ToLower(strings.TrimSpace(status))
+  switch {
+  case status == "updated" || status == "deleted":
+    return 2
+  case isSuperseded(status):
+    return 0
+  }
+  return 1
+}
+
+// true for the STATUS of a dmdSec a later reingest replaced
+func isSuperseded(status string) bool {
+  return strings.Contains(strings.ToLower(status), "superseded")
+}
+
+// get PREMIS:EVENTS and PREMIS:AGENT for an object identified by AdminSec
+func getPremisEvents(a AdminSec) ([]Events, []Agents){
+  var events []Events
+  var agents []Agents
+  for _, digiprov := range a.DigiProvMD {
+    if digiprov.Premis.Mdtype == "PREMIS:EVENT" {
+      events = append(events, premisEvent(digiprov.Premis.PremisEvent))
+    }
+    if digiprov.Premis.Mdtype == "PREMIS:AGENT" {
+      agent := Agents{}
+      agent.IdentifierType = digiprov.Premis.PremisAgent.AgentIdentifierType
+      agent.IdentifierValue = digiprov.Premis.PremisAgent.AgentIdentifierValue
+      agent.Name = digiprov.Premis.PremisAgent.AgentName
+      agent.Type = digiprov.Premis.PremisAgent.AgentType
+      agents = append(agents, agent)
+    }
+  }
+  return events, agents
+}
+
+// convert a PREMIS event to its manifest form
+func premisEvent(e PremisEvent) Events {
+  event := Events{}
+  event.Uuid = e.EventIdentifierValue
+  event.Type = e.EventType
+  event.DateTime = e.EventDate
+  event.Detail = e.EventDetail
+  event.Outcome = e.EventOutcome
+  event.DetailNote = e.EventOutcomeNote
+  tool, err := ParseEventDetail(e.EventDetail)
+  if err == nil {
+    event.Tool = &tool
+  }
+  for _, l := range e.LinkingAgents {
+    event.LinkingAgents = append(event.LinkingAgents, LinkingIdentifier{IdentifierType: l.Type, IdentifierValue: l.Value, Roles: l.Roles})
+  }
+  for _, l := range e.LinkingObjects {
+    event.LinkingObjects = append(event.LinkingObjects, LinkingIdentifier{IdentifierType: l.Type, IdentifierValue: l.Value, Roles: l.Roles})
+  }
+  return event
+}
+
+// get file ID of object from structmap
+func getFileIdDdmdIdStructMap(structmap []StructMap) (map[string][]string) {
+  sm := make(map[string][]string)
+  for _, s1 := range structmap {
+    if s1.Label == "Archivematica default" {
+      unpackDiv(s1.Parent, sm)
+    }
+  }
+  return sm
+}
+
+// recursively iterate over Directory objects to get file ID of non-Directory objects
+func unpackDiv(div Div, sm map[string][]string) (map[string][]string){
+  dmdIds := strings.Split(div.Dmdid, " ")
+  if div.Type == "Item" {
+    sm[div.File.Fileid] = dmdIds // DMDID="dmdSec_3 dmdSec_4"
+  } else if (div.Type == "Directory") {
+    if (div.Label == "objects") {
+      sm[div.Label] = dmdIds
+    }
+    for _, c := range div.Children {
+      unpackDiv(c,sm)
+    }
+  }
+  return sm
+}
+
+// get administrative ID of file in File Section
+func getAmdIdByFileIdFileSec(filesec FileSec, structmap map[string][]string) (map[string]FileMapped){
+  filemap := make(map[string]FileMapped)
+  for _, grp := range filesec.FileGrp {
+    for _, file := range grp.Files {
+      _, ok := structmap[file.ID]
+      if ok {
+        filemapped := FileMapped{}
+        filemapped.Admid = file.Admid
+        filemapped.Dmdid = structmap[file.ID]
+        filemapped.Name = file.FileLocation.Location
+        filemapped.Use = grp.FileType
+        filemap[file.ID] = filemapped
+      }
+    }
+  }
+  return filemap
+}
+
+// return the first event Siegfried performed
+func findSiegfriedEvent(events []Events) (*Events){
+  for _, value := range events {
+    if value.Tool != nil && strings.EqualFold(value.Tool.Program, "Siegfried") {
+       return &value
+    }
+  }
+  return nil
+}
+
+// get parent package name
+func getParentPackage(structMap []StructMap) string {
+  packageName := ""
+  for _, sm := range structMap {
+    if sm.Label == "Archivematica default" {
+      if sm.Parent.Type == "Directory" {
+        packageName = sm.Parent.Label
+      }
+    }
+  }
+  return packageName
+}
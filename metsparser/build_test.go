@@ -0,0 +1,62 @@
+package metsparser
+
+import "testing"
+
+func TestCurrentVersion(t *testing.T) {
+  tests := []struct {
+    name     string
+    versions []DescriptiveSec
+    want     string
+  }{
+    {"none", nil, ""},
+    {"single", []DescriptiveSec{{ID: "a"}}, "a"},
+    {"updated outranks an original of the same date", []DescriptiveSec{
+      {ID: "a", Status: "updated", Created: "2021-01-01T00:00:00"},
+      {ID: "b", Status: "original", Created: "2021-01-01T00:00:00"},
+    }, "a"},
+    {"updated outranks undated originals", []DescriptiveSec{
+      {ID: "a", Status: "original"},
+      {ID: "b", Status: "updated", Created: "2021-01-01T00:00:00"},
+      {ID: "c", Status: "original"},
+    }, "b"},
+    {"deleted outranks an original", []DescriptiveSec{
+      {ID: "a", Status: "deleted", Created: "2020-01-01T00:00:00"},
+      {ID: "b", Created: "2021-01-01T00:00:00"},
+    }, "a"},
+    {"latest created", []DescriptiveSec{
+      {ID: "a", Status: "updated", Created: "2021-06-01T00:00:00"},
+      {ID: "b", Status: "updated", Created: "2021-01-01T00:00:00"},
+    }, "a"},
+    {"created across time zones", []DescriptiveSec{
+      {ID: "a", Status: "updated", Created: "2021-01-01T10:00:00+02:00"},
+      {ID: "b", Status: "updated", Created: "2021-01-01T09:00:00+00:00"},
+    }, "b"},
+    {"dated beats undated", []DescriptiveSec{
+      {ID: "a", Status: "updated", Created: "2021-01-01T00:00:00"},
+      {ID: "b", Status: "updated"},
+    }, "a"},
+    {"last listed breaks a tie", []DescriptiveSec{
+      {ID: "a", Status: "updated", Created: "2021-01-01T00:00:00Z"},
+      {ID: "b", Status: "updated", Created: "2021-01-01T00:00:00+00:00"},
+    }, "b"},
+    {"superseded left out", []DescriptiveSec{
+      {ID: "a", Status: "original"},
+      {ID: "b", Status: "update-superseded", Created: "2022-01-01T00:00:00"},
+    }, "a"},
+    {"all superseded", []DescriptiveSec{
+      {ID: "a", Status: "original-superseded", Created: "2021-01-01T00:00:00"},
+      {ID: "b", Status: "update-superseded", Created: "2020-01-01T00:00:00"},
+    }, "a"},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      got := ""
+      if current := currentVersion(tt.versions); current != nil {
+        got = current.ID
+      }
+      if got != tt.want {
+        t.Errorf("got %q, want %q", got, tt.want)
+      }
+    })
+  }
+}
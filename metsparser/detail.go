@@ -0,0 +1,111 @@
+package metsparser
+
+import (
+  "fmt"
+  "strings"
+)
+
+// ParseEventDetail reads the tool that performed an event from its detail,
+// written by Archivematica as program="X"; version="Y" with any further
+// parameters (virusDefinitions, ArchivematicaFPRCommandID...) kept in Params
+// under lowercased keys. Returns ErrMalformedDetail if the detail has no
+// key=value pair at all.
+func ParseEventDetail(detail string) (EventTool, error) {
+  fields, _ := parseDetail(detail)
+  if len(fields) == 0 {
+    return EventTool{}, fmt.Errorf("%w: %q", ErrMalformedDetail, detail)
+  }
+  tool := EventTool{Program: fields["program"], Version: fields["version"]}
+  for key, value := range fields {
+    if key == "program" || key == "version" {
+      continue
+    }
+    if tool.Params == nil {
+      tool.Params = make(map[string]string)
+    }
+    tool.Params[key] = value
+  }
+  return tool, nil
+}
+
+// parseDetail splits an event detail or outcome note written as
+// key="value"; key="value" into its fields. Values may be unquoted, quoted
+// values may hold semicolons and escaped quotes, and keys are lowercased.
+// Text that isn't a key=value pair is returned apart, in order.
+func parseDetail(detail string) (map[string]string, []string) {
+  fields := make(map[string]string)
+  var text []string
+  i := 0
+  for i < len(detail) {
+    // skip separators
+    for i < len(detail) && (detail[i] == ';' || detail[i] == ' ' || detail[i] == '\t' || detail[i] == '\n' || detail[i] == '\r') {
+      i++
+    }
+    if i >= len(detail) {
+      break
+    }
+    start := i
+    for i < len(detail) && detail[i] != '=' && detail[i] != ';' && detail[i] != '"' {
+      i++
+    }
+    if i >= len(detail) || detail[i] != '=' {
+      // free text up to the next separator, quotes included
+      i = start + segmentEnd(detail[start:])
+      text = append(text, strings.TrimSpace(detail[start:i]))
+      continue
+    }
+    key := strings.ToLower(strings.TrimSpace(detail[start:i]))
+    i++
+    for i < len(detail) && detail[i] == ' ' {
+      i++
+    }
+    var value string
+    if i < len(detail) && detail[i] == '"' {
+      value, i = quoted(detail, i)
+    } else {
+      end := i + strings.IndexByte(detail[i:]+";", ';')
+      value = strings.TrimSpace(detail[i:end])
+      i = end
+    }
+    if key == "" {
+      text = append(text, value)
+      continue
+    }
+    fields[key] = value
+  }
+  return fields, text
+}
+
+// the quoted string starting at i and the index after its closing quote
+func quoted(s string, i int) (string, int) {
+  var b strings.Builder
+  for i++; i < len(s); i++ {
+    switch {
+    case s[i] == '\\' && i+1 < len(s) && s[i+1] == '"':
+      b.WriteByte('"')
+      i++
+    case s[i] == '"':
+      return b.String(), i + 1
+    default:
+      b.WriteByte(s[i])
+    }
+  }
+  // unterminated, take the rest
+  return b.String(), i
+}
+
+// length of the text up to the next semicolon outside quotes
+func segmentEnd(s string) int {
+  inQuotes := false
+  for i := 0; i < len(s); i++ {
+    switch s[i] {
+    case '"':
+      inQuotes = !inQuotes
+    case ';':
+      if !inQuotes {
+        return i
+      }
+    }
+  }
+  return len(s)
+}
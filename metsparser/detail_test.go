@@ -0,0 +1,76 @@
+package metsparser
+
+import (
+  "errors"
+  "reflect"
+  "testing"
+)
+
+func TestParseEventDetail(t *testing.T) {
+  tests := []struct {
+    name   string
+    detail string
+    want   EventTool
+  }{
+    {"archivematica", `program="ClamAV (clamd)"; version="ClamAV 0.103.2"; virusDefinitions="26311/Tue Oct 12"`,
+      EventTool{Program: "ClamAV (clamd)", Version: "ClamAV 0.103.2", Params: map[string]string{"virusdefinitions": "26311/Tue Oct 12"}}},
+    {"unquoted", `program=Siegfried; version=1.9.1`,
+      EventTool{Program: "Siegfried", Version: "1.9.1"}},
+    {"keys in any case", `Program="Siegfried"; VERSION="1.9.1"`,
+      EventTool{Program: "Siegfried", Version: "1.9.1"}},
+    {"semicolon and quotes in a value", `program="a; b"; version="say \"hi\""`,
+      EventTool{Program: "a; b", Version: `say "hi"`}},
+    {"spaces around separators", ` program = "FITS" ;version="1.5.0";`,
+      EventTool{Program: "FITS", Version: "1.5.0"}},
+    {"extra keys", `program="ffmpeg"; version="4.4"; ArchivematicaFPRCommandID="0e6ed8bc"; arguments="-i in out"`,
+      EventTool{Program: "ffmpeg", Version: "4.4", Params: map[string]string{"archivematicafprcommandid": "0e6ed8bc", "arguments": "-i in out"}}},
+    {"free text kept out", `Checked the file; program="bagit"`,
+      EventTool{Program: "bagit"}},
+    {"no program", `algorithm="sha256"`,
+      EventTool{Params: map[string]string{"algorithm": "sha256"}}},
+    {"unterminated quote", `program="FITS; version=1`,
+      EventTool{Program: "FITS; version=1"}},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      got, err := ParseEventDetail(tt.detail)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if !reflect.DeepEqual(got, tt.want) {
+        t.Errorf("got %+v, want %+v", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestParseEventDetailMalformed(t *testing.T) {
+  for _, detail := range []string{"", "   ", "Virus scan passed", `"quoted text"; more text`, `="no key"`} {
+    _, err := ParseEventDetail(detail)
+    if !errors.Is(err, ErrMalformedDetail) {
+      t.Errorf("%q: got %v, want ErrMalformedDetail", detail, err)
+    }
+  }
+}
+
+func TestParseDetail(t *testing.T) {
+  tests := []struct {
+    detail string
+    fields map[string]string
+    text   []string
+  }{
+    {`format="PDF"; version="1.4"; result="Well-Formed and valid"`,
+      map[string]string{"format": "PDF", "version": "1.4", "result": "Well-Formed and valid"}, nil},
+    {`Not well-formed; "a; b"; key=value`,
+      map[string]string{"key": "value"}, []string{"Not well-formed", `"a; b"`}},
+    {`=orphan; k=`,
+      map[string]string{"k": ""}, []string{"orphan"}},
+    {"", map[string]string{}, nil},
+  }
+  for _, tt := range tests {
+    fields, text := parseDetail(tt.detail)
+    if !reflect.DeepEqual(fields, tt.fields) || !reflect.DeepEqual(text, tt.text) {
+      t.Errorf("%q: got %v %q, want %v %q", tt.detail, fields, text, tt.fields, tt.text)
+    }
+  }
+}
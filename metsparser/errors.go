@@ -0,0 +1,58 @@
+package metsparser
+
+import (
+  "errors"
+  "fmt"
+)
+
+// ErrMissingDmdSec is returned when the METS has no descriptive metadata (dmdSec)
+var ErrMissingDmdSec = errors.New("descriptive metadata (dmdSec) missing")
+
+// ErrEmptyBytes is returned when a PREMIS object does not record its size
+var ErrEmptyBytes = errors.New("empty bytes")
+
+// ErrMalformedDetail is returned when an event detail can't be parsed
+var ErrMalformedDetail = errors.New("malformed event detail")
+
+// ErrMalformedPolicy is returned when a format policy can't be decoded as JSON or YAML
+var ErrMalformedPolicy = errors.New("malformed format policy")
+
+// ErrMetsNotFound is returned when a packed AIP has no data/METS.<uuid>.xml
+var ErrMetsNotFound = errors.New("METS file not found in package")
+
+// ErrUnsupportedPackage is returned for an archive format OpenAIP can't read
+var ErrUnsupportedPackage = errors.New("unsupported package format")
+
+// ErrCriticalEvent is returned when an event type listed in Options.CriticalEvents failed
+var ErrCriticalEvent = errors.New("critical event failed")
+
+// ErrUnknownSchemaVersion is returned for a manifest version with no published schema
+var ErrUnknownSchemaVersion = errors.New("unknown schema version")
+
+// ParseError reports a METS document that could not be decoded
+type ParseError struct {
+  Err error
+}
+
+func (e *ParseError) Error() string {
+  return "parse METS: " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+  return e.Err
+}
+
+// FileError reports a problem with the metadata of one object, identified by its amdSec ID
+type FileError struct {
+  Admid string
+  Field string
+  Err   error
+}
+
+func (e *FileError) Error() string {
+  return fmt.Sprintf("%s: %s: %v", e.Admid, e.Field, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+  return e.Err
+}
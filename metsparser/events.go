@@ -0,0 +1,68 @@
+package metsparser
+
+// AgentId returns the key of an agent in the package agents, made of its
+// PREMIS identifier so the same agent has the same ID in every manifest
+func AgentId(identifierType string, identifierValue string) string {
+  return identifierType + ":" + identifierValue
+}
+
+// agents of every file, listed once in the order they are first seen
+type packageAgents struct {
+  agents []Agents
+  seen   map[string]bool
+}
+
+// add agents to the package and return their IDs
+func (p *packageAgents) add(agents []Agents) []string {
+  if p.seen == nil {
+    p.seen = make(map[string]bool)
+  }
+  var ids []string
+  for _, agent := range agents {
+    agent.ID = AgentId(agent.IdentifierType, agent.IdentifierValue)
+    ids = append(ids, agent.ID)
+    if !p.seen[agent.ID] {
+      p.seen[agent.ID] = true
+      p.agents = append(p.agents, agent)
+    }
+  }
+  return ids
+}
+
+// set the package agent ID on every agent an event links to
+func (p *packageAgents) link(events []Events) []Events {
+  for i := range events {
+    for j := range events[i].LinkingAgents {
+      l := &events[i].LinkingAgents[j]
+      l.AgentId = AgentId(l.IdentifierType, l.IdentifierValue)
+    }
+  }
+  return events
+}
+
+// AgentsOf returns the agents an event of a file links to, in the order the
+// event lists them, from the file's own agents or the package agents. Links
+// to agents neither describes are skipped.
+func (m *ObjectMetsManifest) AgentsOf(file FilesMets, e Events) []Agents {
+  var agents []Agents
+  for _, l := range e.LinkingAgents {
+    id := AgentId(l.IdentifierType, l.IdentifierValue)
+    agent, ok := findAgent(file.DescriptiveMD.Agents, id)
+    if !ok {
+      agent, ok = findAgent(m.Agents, id)
+    }
+    if ok {
+      agents = append(agents, agent)
+    }
+  }
+  return agents
+}
+
+func findAgent(agents []Agents, id string) (Agents, bool) {
+  for _, agent := range agents {
+    if AgentId(agent.IdentifierType, agent.IdentifierValue) == id {
+      return agent, true
+    }
+  }
+  return Agents{}, false
+}
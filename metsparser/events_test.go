@@ -0,0 +1,93 @@
+package metsparser
+
+import (
+  "bytes"
+  "io/ioutil"
+  "reflect"
+  "testing"
+)
+
+// names of the agents an event links to, through AgentsOf
+func agentNames(m *ObjectMetsManifest, file FilesMets, e Events) []string {
+  var names []string
+  for _, agent := range m.AgentsOf(file, e) {
+    names = append(names, AgentId(agent.IdentifierType, agent.IdentifierValue)+" "+agent.Name)
+  }
+  return names
+}
+
+// AgentsOf resolves the same agents whether a file embeds them or refers to
+// the package agents
+func TestAgentsOf(t *testing.T) {
+  data, err := ioutil.ReadFile("testdata/METS.abc.xml")
+  if err != nil {
+    t.Fatal(err)
+  }
+  build := func(embed bool) *ObjectMetsManifest {
+    mets, err := Parse(bytes.NewReader(data))
+    if err != nil {
+      t.Fatal(err)
+    }
+    m, err := BuildManifest(mets, Options{EmbedAgents: embed})
+    if err != nil {
+      t.Fatal(err)
+    }
+    return m
+  }
+  referenced, embedded := build(false), build(true)
+
+  resolved := 0
+  for i, file := range referenced.Manifest.Files {
+    if file.DescriptiveMD.Agents != nil {
+      t.Fatalf("%s: agents embedded without -embed-agents", file.FileName)
+    }
+    embeddedFile := embedded.Manifest.Files[i]
+    if len(embeddedFile.DescriptiveMD.Agents) == 0 {
+      t.Fatalf("%s: no agents embedded", file.FileName)
+    }
+    for j, e := range file.DescriptiveMD.Events {
+      want := agentNames(referenced, file, e)
+      resolved += len(want)
+      got := agentNames(embedded, embeddedFile, embeddedFile.DescriptiveMD.Events[j])
+      if !reflect.DeepEqual(got, want) {
+        t.Errorf("%s %s: embedded agents %v, referenced %v", file.FileName, e.Type, got, want)
+      }
+    }
+  }
+  if resolved == 0 {
+    t.Fatal("no event links to an agent")
+  }
+
+  // embedded agents need no package agents, referenced ones do
+  embedded.Agents, referenced.Agents = nil, nil
+  for i, file := range embedded.Manifest.Files {
+    for j, e := range file.DescriptiveMD.Events {
+      if len(e.LinkingAgents) > 0 && len(agentNames(embedded, file, e)) == 0 {
+        t.Errorf("%s %s: embedded agents not found", file.FileName, e.Type)
+      }
+      referencedFile := referenced.Manifest.Files[i]
+      if names := agentNames(referenced, referencedFile, referencedFile.DescriptiveMD.Events[j]); names != nil {
+        t.Errorf("%s %s: agents %v without package agents", file.FileName, e.Type, names)
+      }
+    }
+  }
+}
+
+// links to agents neither the file nor the package describes are skipped
+func TestAgentsOfUnknownAgent(t *testing.T) {
+  m := &ObjectMetsManifest{Agents: []Agents{{IdentifierType: "preservation system", IdentifierValue: "Archivematica-1.13", Name: "Archivematica"}}}
+  file := FilesMets{}
+  file.DescriptiveMD.Agents = []Agents{{IdentifierType: "Archivematica user pk", IdentifierValue: "1", Name: "username=\"kim\""}}
+  e := Events{LinkingAgents: []LinkingIdentifier{
+    {IdentifierType: "Archivematica user pk", IdentifierValue: "1"},
+    {IdentifierType: "repository code", IdentifierValue: "unknown"},
+    {IdentifierType: "preservation system", IdentifierValue: "Archivematica-1.13"},
+  }}
+  want := []string{
+    AgentId("Archivematica user pk", "1") + " username=\"kim\"",
+    AgentId("preservation system", "Archivematica-1.13") + " Archivematica",
+  }
+  if got := agentNames(m, file, e); !reflect.DeepEqual(got, want) {
+    t.Errorf("agents %v, want %v", got, want)
+  }
+}
@@ -0,0 +1,74 @@
+package metsparser
+
+import (
+  "strings"
+)
+
+// manifest sections files are listed in, named after their JSON field
+const (
+  SectionOriginals   = "files"
+  SectionDerivatives = "derivatives"
+  SectionSupporting  = "supporting_documentation"
+)
+
+// fileGrp USE values of files Archivematica derives from the originals
+var derivativeUses = map[string]bool{
+  "preservation": true,
+  "access":       true,
+  "text/ocr":     true,
+  "thumbnail":    true,
+}
+
+// UseSection returns the manifest section of a file from its fileGrp USE.
+// Originals, and files outside any fileGrp, are listed under files; every
+// other group (submissionDocumentation, metadata, license...) is supporting
+// documentation.
+func UseSection(use string) string {
+  switch {
+  case use == "" || use == "original":
+    return SectionOriginals
+  case derivativeUses[use]:
+    return SectionDerivatives
+  }
+  return SectionSupporting
+}
+
+// Add lists a file in the section of its fileGrp
+func (m *ManifestMets) Add(file FilesMets) {
+  switch UseSection(file.Use) {
+  case SectionOriginals:
+    m.Files = append(m.Files, file)
+  case SectionDerivatives:
+    m.Derivatives = append(m.Derivatives, file)
+  default:
+    m.Supporting = append(m.Supporting, file)
+  }
+}
+
+// AllFiles returns every file of the manifest, whatever its section
+func (m *ManifestMets) AllFiles() []*FilesMets {
+  var files []*FilesMets
+  for _, section := range [][]FilesMets{m.Files, m.Derivatives, m.Supporting} {
+    for i := range section {
+      files = append(files, &section[i])
+    }
+  }
+  return files
+}
+
+// true if files of a fileGrp are listed with these options
+func (o Options) listsUse(use string) bool {
+  if len(o.IncludeUse) > 0 && !containsFold(o.IncludeUse, use) {
+    return false
+  }
+  return !containsFold(o.ExcludeUse, use)
+}
+
+func containsFold(values []string, value string) bool {
+  for _, v := range values {
+    if strings.EqualFold(v, value) {
+      return true
+    }
+  }
+  return false
+}
@@ -0,0 +1,246 @@
+package metsparser
+
+import (
+  "crypto/md5"
+  "crypto/rand"
+  "crypto/sha1"
+  "crypto/sha256"
+  "crypto/sha512"
+  "encoding/hex"
+  "fmt"
+  "hash"
+  "io"
+  "os"
+  "path/filepath"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// fixity check outcomes per file
+const (
+  FixityOk       = "ok"
+  FixityMissing  = "missing"
+  FixityMismatch = "mismatch"
+  FixityError    = "error"
+)
+
+// FixityReport compares the files recorded in a METS with a payload on disk
+type FixityReport struct {
+  Valid      bool           `json:"valid"`
+  Files      []FixityResult `json:"files"`
+  Missing    []string       `json:"missing"`
+  Extra      []string       `json:"extra"`
+  Mismatched []string       `json:"mismatched"`
+  Errors     []string       `json:"errors"`
+}
+
+// FixityResult is the check of one file against its PREMIS fixity and size
+type FixityResult struct {
+  FileName     string `json:"filename"`
+  Algorithm    string `json:"algorithm"`
+  Expected     string `json:"expected"`
+  Actual       string `json:"actual"`
+  ExpectedSize int64  `json:"expected_size"`
+  ActualSize   int64  `json:"actual_size"`
+  Status       string `json:"status"`
+  Message      string `json:"message,omitempty"`
+}
+
+// Verify recomputes the checksum and size of every file in the METS, found
+// relative to dir, and lists files on disk the METS doesn't know about
+func Verify(mets *Mets, dir string) (FixityReport, error) {
+  report := FixityReport{
+    Files:      []FixityResult{},
+    Missing:    []string{},
+    Extra:      []string{},
+    Mismatched: []string{},
+    Errors:     []string{},
+  }
+  structmap := getFileIdDdmdIdStructMap(mets.StructMap)
+  locations := make(map[string]string)
+  for _, value := range getAmdIdByFileIdFileSec(mets.FileSec, structmap) {
+    locations[value.Admid] = value.Name
+  }
+
+  known := make(map[string]bool)
+  roots := make(map[string]bool)
+  for _, a := range mets.AdminSec {
+    if (a.TechnicalMD.ID == "") {
+      continue
+    }
+    name, ok := locations[a.ID]
+    if !ok {
+      report.Errors = append(report.Errors, a.ID+": no file location in fileSec")
+      continue
+    }
+    known[filepath.Clean(filepath.FromSlash(name))] = true
+    roots[strings.SplitN(filepath.ToSlash(filepath.Clean(name)), "/", 2)[0]] = true
+
+    result := verifyFile(dir, name, a.TechnicalMD.PremisObject)
+    report.Files = append(report.Files, result)
+    switch result.Status {
+    case FixityMissing:
+      report.Missing = append(report.Missing, name)
+    case FixityMismatch:
+      report.Mismatched = append(report.Mismatched, name)
+    case FixityError:
+      report.Errors = append(report.Errors, name+": "+result.Message)
+    }
+  }
+
+  // anything on disk under the directories the METS files live in
+  var rootNames []string
+  for root := range roots {
+    rootNames = append(rootNames, root)
+  }
+  sort.Strings(rootNames)
+  for _, root := range rootNames {
+    if root == ".." || root == "." {
+      continue
+    }
+    err := filepath.Walk(filepath.Join(dir, root), func(path string, info os.FileInfo, err error) error {
+      if os.IsNotExist(err) {
+        return nil
+      }
+      if err != nil {
+        return err
+      }
+      if info.IsDir() {
+        return nil
+      }
+      rel, err := filepath.Rel(dir, path)
+      if err != nil {
+        return err
+      }
+      if !known[rel] {
+        report.Extra = append(report.Extra, filepath.ToSlash(rel))
+      }
+      return nil
+    })
+    if err != nil {
+      return report, err
+    }
+  }
+
+  report.Valid = len(report.Missing) == 0 && len(report.Extra) == 0 && len(report.Mismatched) == 0 && len(report.Errors) == 0
+  return report, nil
+}
+
+// check one file's size and checksum
+func verifyFile(dir string, name string, object PremisObject) FixityResult {
+  result := FixityResult{FileName: name, Algorithm: object.Hashtype, Expected: strings.ToLower(object.Hashvalue)}
+  size, err := strconv.ParseInt(object.Bytes, 10, 64)
+  if err != nil {
+    result.Status = FixityError
+    result.Message = "invalid size " + strconv.Quote(object.Bytes)
+    return result
+  }
+  result.ExpectedSize = size
+
+  path := filepath.Join(dir, filepath.FromSlash(name))
+  rel, err := filepath.Rel(dir, path)
+  if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+    result.Status = FixityError
+    result.Message = "location is outside the package directory"
+    return result
+  }
+
+  h := newHash(object.Hashtype)
+  if h == nil {
+    result.Status = FixityError
+    result.Message = "unsupported algorithm " + strconv.Quote(object.Hashtype)
+    return result
+  }
+
+  file, err := os.Open(path)
+  if os.IsNotExist(err) {
+    result.Status = FixityMissing
+    return result
+  }
+  if err != nil {
+    result.Status = FixityError
+    result.Message = err.Error()
+    return result
+  }
+  defer file.Close()
+  result.ActualSize, err = io.Copy(h, file)
+  if err != nil {
+    result.Status = FixityError
+    result.Message = err.Error()
+    return result
+  }
+  result.Actual = hex.EncodeToString(h.Sum(nil))
+
+  result.Status = FixityOk
+  var problems []string
+  if result.ActualSize != result.ExpectedSize {
+    problems = append(problems, fmt.Sprintf("size %d, expected %d", result.ActualSize, result.ExpectedSize))
+  }
+  if result.Actual != result.Expected {
+    problems = append(problems, fmt.Sprintf("%s %s, expected %s", object.Hashtype, result.Actual, result.Expected))
+  }
+  if len(problems) > 0 {
+    result.Status = FixityMismatch
+    result.Message = strings.Join(problems, "; ")
+  }
+  return result
+}
+
+// hash for a PREMIS messageDigestAlgorithm, nil if unsupported
+func newHash(algorithm string) hash.Hash {
+  switch strings.Replace(strings.ToLower(algorithm), "-", "", -1) {
+  case "md5":
+    return md5.New()
+  case "sha1":
+    return sha1.New()
+  case "sha256":
+    return sha256.New()
+  case "sha512":
+    return sha512.New()
+  }
+  return nil
+}
+
+// RecordFixityCheck adds a fixity check event with the outcome of the report
+// to each file of the manifest that was checked, then updates the event errors
+// and status. It returns ErrCriticalEvent if a check failed and "fixity check"
+// is one of criticalEvents.
+func RecordFixityCheck(m *ObjectMetsManifest, report FixityReport, when time.Time, criticalEvents []string) error {
+  results := make(map[string]FixityResult)
+  for _, r := range report.Files {
+    results[r.FileName] = r
+  }
+  for _, file := range m.Manifest.AllFiles() {
+    r, ok := results[file.FileName]
+    if !ok {
+      continue
+    }
+    event := Events{}
+    event.Uuid = newUuid()
+    event.Type = "fixity check"
+    event.DateTime = when.Format(time.RFC3339)
+    event.Detail = fmt.Sprintf("program=\"canopus-mets-parser\"; algorithm=\"%s\"", r.Algorithm)
+    event.Tool = &EventTool{Program: "canopus-mets-parser", Params: map[string]string{"algorithm": r.Algorithm}}
+    event.Outcome = "Pass"
+    if r.Status != FixityOk {
+      event.Outcome = "Fail"
+    }
+    event.DetailNote = r.Status
+    if r.Message != "" {
+      event.DetailNote += ": " + r.Message
+    }
+    file.DescriptiveMD.Events = append(file.DescriptiveMD.Events, event)
+  }
+  return UpdateStatus(m, criticalEvents)
+}
+
+// random (version 4) UUID for events created here
+func newUuid() string {
+  b := make([]byte, 16)
+  rand.Read(b)
+  b[6] = (b[6] & 0x0f) | 0x40
+  b[8] = (b[8] & 0x3f) | 0x80
+  return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
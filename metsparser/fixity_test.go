@@ -0,0 +1,196 @@
+package metsparser
+
+import (
+  "bytes"
+  "crypto/md5"
+  "crypto/sha256"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "reflect"
+  "strings"
+  "testing"
+  "time"
+)
+
+// a file as a METS records it
+type recordedFile struct {
+  location  string
+  algorithm string
+  digest    string
+  size      string
+}
+
+// a METS listing files, each with an amdSec holding its PREMIS fixity
+func fixityMets(t *testing.T, files ...recordedFile) *Mets {
+  var amdSecs, fileSec, divs strings.Builder
+  for i, f := range files {
+    fmt.Fprintf(&amdSecs, `<mets:amdSec ID="amdSec_%d"><mets:techMD ID="techMD_%d"><mets:mdWrap MDTYPE="PREMIS:OBJECT"><mets:xmlData><premis:object>
+      <premis:objectCharacteristics><premis:fixity><premis:messageDigestAlgorithm>%s</premis:messageDigestAlgorithm><premis:messageDigest>%s</premis:messageDigest></premis:fixity><premis:size>%s</premis:size></premis:objectCharacteristics>
+      </premis:object></mets:xmlData></mets:mdWrap></mets:techMD></mets:amdSec>`, i, i, f.algorithm, f.digest, f.size)
+    fmt.Fprintf(&fileSec, `<mets:file ID="file-%d" ADMID="amdSec_%d"><mets:FLocat xlink:href="%s" LOCTYPE="OTHER"/></mets:file>`, i, i, f.location)
+    fmt.Fprintf(&divs, `<mets:div TYPE="Item" LABEL="%d"><mets:fptr FILEID="file-%d"/></mets:div>`, i, i)
+  }
+  xml := `<mets:mets xmlns:mets="http://www.loc.gov/METS/" xmlns:premis="http://www.loc.gov/premis/v3" xmlns:xlink="http://www.w3.org/1999/xlink">` +
+    amdSecs.String() +
+    `<mets:fileSec><mets:fileGrp USE="original">` + fileSec.String() + `</mets:fileGrp></mets:fileSec>
+    <mets:structMap TYPE="physical" LABEL="Archivematica default"><mets:div TYPE="Directory" LABEL="objects">` + divs.String() + `</mets:div></mets:structMap>
+    </mets:mets>`
+  mets, err := Parse(bytes.NewReader([]byte(xml)))
+  if err != nil {
+    t.Fatal(err)
+  }
+  return mets
+}
+
+func sha256Hex(s string) string {
+  sum := sha256.Sum256([]byte(s))
+  return hex.EncodeToString(sum[:])
+}
+
+func TestVerify(t *testing.T) {
+  dir, err := ioutil.TempDir("", "payload")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+  for name, content := range map[string]string{
+    "objects/ok.txt":          "hello",
+    "objects/md5.txt":         "hello",
+    "objects/changed.txt":     "hellO",
+    "objects/resized.txt":     "hello",
+    "objects/unsupported.txt": "hello",
+    "objects/sub/extra.txt":   "extra",
+    "outside.txt":             "not under a METS directory",
+  } {
+    path := filepath.Join(dir, filepath.FromSlash(name))
+    os.MkdirAll(filepath.Dir(path), 0755)
+    err = ioutil.WriteFile(path, []byte(content), 0644)
+    if err != nil {
+      t.Fatal(err)
+    }
+  }
+  md5Sum := md5.Sum([]byte("hello"))
+
+  mets := fixityMets(t,
+    recordedFile{"objects/ok.txt", "sha256", sha256Hex("hello"), "5"},
+    recordedFile{"objects/md5.txt", "MD5", strings.ToUpper(hex.EncodeToString(md5Sum[:])), "5"},
+    recordedFile{"objects/changed.txt", "sha256", sha256Hex("hello"), "5"},
+    recordedFile{"objects/resized.txt", "sha-256", sha256Hex("hello"), "6"},
+    recordedFile{"objects/unsupported.txt", "crc32", "3610a686", "5"},
+    recordedFile{"objects/missing.txt", "sha256", sha256Hex("hello"), "5"},
+    recordedFile{"objects/bad-size.txt", "sha256", sha256Hex("hello"), "five"},
+    recordedFile{"../outside.txt", "sha256", sha256Hex("hello"), "5"},
+  )
+  report, err := Verify(mets, dir)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  statuses := make(map[string]string)
+  for _, f := range report.Files {
+    statuses[f.FileName] = f.Status
+  }
+  wantStatuses := map[string]string{
+    "objects/ok.txt":          FixityOk,
+    "objects/md5.txt":         FixityOk,
+    "objects/changed.txt":     FixityMismatch,
+    "objects/resized.txt":     FixityMismatch,
+    "objects/unsupported.txt": FixityError,
+    "objects/missing.txt":     FixityMissing,
+    "objects/bad-size.txt":    FixityError,
+    "../outside.txt":          FixityError,
+  }
+  if !reflect.DeepEqual(statuses, wantStatuses) {
+    t.Errorf("statuses %v, want %v", statuses, wantStatuses)
+  }
+  if report.Valid {
+    t.Error("report is valid")
+  }
+  if !reflect.DeepEqual(report.Missing, []string{"objects/missing.txt"}) {
+    t.Errorf("missing %v", report.Missing)
+  }
+  if !reflect.DeepEqual(report.Mismatched, []string{"objects/changed.txt", "objects/resized.txt"}) {
+    t.Errorf("mismatched %v", report.Mismatched)
+  }
+  // outside.txt is on disk, but not under a directory the METS files are in
+  if !reflect.DeepEqual(report.Extra, []string{"objects/sub/extra.txt"}) {
+    t.Errorf("extra %v", report.Extra)
+  }
+  wantErrors := []string{
+    `objects/unsupported.txt: unsupported algorithm "crc32"`,
+    `objects/bad-size.txt: invalid size "five"`,
+    "../outside.txt: location is outside the package directory",
+  }
+  if !reflect.DeepEqual(report.Errors, wantErrors) {
+    t.Errorf("errors %q, want %q", report.Errors, wantErrors)
+  }
+
+  // a payload matching the METS
+  report, err = Verify(fixityMets(t, recordedFile{"objects/ok.txt", "sha256", sha256Hex("hello"), "5"},
+    recordedFile{"objects/sub/extra.txt", "sha256", sha256Hex("extra"), "5"},
+    recordedFile{"objects/md5.txt", "md5", hex.EncodeToString(md5Sum[:]), "5"},
+    recordedFile{"objects/changed.txt", "sha256", sha256Hex("hellO"), "5"},
+    recordedFile{"objects/resized.txt", "sha256", sha256Hex("hello"), "5"},
+    recordedFile{"objects/unsupported.txt", "sha256", sha256Hex("hello"), "5"}), dir)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !report.Valid {
+    t.Errorf("got %+v, want a valid report", report)
+  }
+}
+
+func TestRecordFixityCheck(t *testing.T) {
+  when := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+  build := func() *ObjectMetsManifest {
+    m, err := BuildManifest(parseFixture(t, "METS.abc.xml"), Options{RightsDate: when})
+    if err != nil {
+      t.Fatal(err)
+    }
+    return m
+  }
+  built := build()
+  original := built.Manifest.Files[0].FileName
+  // the fixture already has a failed validation event
+  eventErrors := len(built.Manifest.Files[0].EventErrors)
+
+  report := FixityReport{Files: []FixityResult{{FileName: original, Algorithm: "sha256", Status: FixityOk}}}
+  m := build()
+  err := RecordFixityCheck(m, report, when, []string{"fixity check"})
+  if err != nil {
+    t.Fatal(err)
+  }
+  events := m.Manifest.Files[0].DescriptiveMD.Events
+  event := events[len(events)-1]
+  if event.Type != "fixity check" || event.Outcome != "Pass" || event.DateTime != "2026-01-01T00:00:00Z" || event.Tool.Params["algorithm"] != "sha256" {
+    t.Errorf("got event %+v", event)
+  }
+  if m.Status != built.Status || len(m.Manifest.Files[0].EventErrors) != eventErrors {
+    t.Errorf("status %q, event errors %+v, want them unchanged", m.Status, m.Manifest.Files[0].EventErrors)
+  }
+
+  report.Files[0].Status = FixityMismatch
+  report.Files[0].Message = "size 4, expected 5"
+  m = build()
+  err = RecordFixityCheck(m, report, when, nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  file := m.Manifest.Files[0]
+  if m.Status != StatusFailed || len(file.EventErrors) != eventErrors+1 || file.EventErrors[eventErrors].Type != "fixity check" {
+    t.Errorf("status %q, event errors %+v, want the failed fixity check", m.Status, file.EventErrors)
+  }
+  if note := file.DescriptiveMD.Events[len(file.DescriptiveMD.Events)-1].DetailNote; note != "mismatch: size 4, expected 5" {
+    t.Errorf("detail note %q", note)
+  }
+
+  m = build()
+  err = RecordFixityCheck(m, report, when, []string{"fixity check"})
+  if !errors.Is(err, ErrCriticalEvent) {
+    t.Errorf("got %v, want ErrCriticalEvent", err)
+  }
+}
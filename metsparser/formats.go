@@ -0,0 +1,120 @@
+package metsparser
+
+import (
+  "sort"
+  "strings"
+)
+
+// FormatReport is the format profile of one or many packages, from the
+// primary (PREMIS) match of every file
+type FormatReport struct {
+  Packages     []PackageFormats   `json:"packages"`
+  Formats      []FormatCount      `json:"formats"`
+  Unidentified []UnidentifiedFile `json:"unidentified"`
+
+  all formatCounts
+}
+
+// PackageFormats is the format profile of one package
+type PackageFormats struct {
+  Package      string        `json:"package"`
+  Formats      []FormatCount `json:"formats"`
+  Unidentified int64         `json:"unidentified"`
+}
+
+// FormatCount is the number and total size of files of one format
+type FormatCount struct {
+  Puid     string `json:"puid"`
+  Format   string `json:"format"`
+  Version  string `json:"version"`
+  Mime     string `json:"mime"`
+  Files    int64  `json:"files"`
+  Bytes    int64  `json:"bytes"`
+  Packages int64  `json:"packages,omitempty"`
+}
+
+// UnidentifiedFile is a file no format was identified for
+type UnidentifiedFile struct {
+  Package  string `json:"package"`
+  FileName string `json:"filename"`
+  Use      string `json:"use"`
+  Bytes    int64  `json:"bytes"`
+}
+
+// format counts keyed by PUID, name, version and MIME type
+type formatCounts struct {
+  counts map[string]*FormatCount
+}
+
+func (c *formatCounts) add(match Matches, files int64, bytes int64) *FormatCount {
+  if c.counts == nil {
+    c.counts = make(map[string]*FormatCount)
+  }
+  key := strings.Join([]string{strings.ToLower(match.ID), match.Format, match.Version, match.Mime}, "\x00")
+  count, ok := c.counts[key]
+  if !ok {
+    count = &FormatCount{Puid: match.ID, Format: match.Format, Version: match.Version, Mime: match.Mime}
+    c.counts[key] = count
+  }
+  count.Files += files
+  count.Bytes += bytes
+  return count
+}
+
+// the counts, most files first
+func (c *formatCounts) list() []FormatCount {
+  formats := []FormatCount{}
+  for _, count := range c.counts {
+    formats = append(formats, *count)
+  }
+  sort.Slice(formats, func(i, j int) bool {
+    a, b := formats[i], formats[j]
+    if a.Files != b.Files {
+      return a.Files > b.Files
+    }
+    if a.Puid != b.Puid {
+      return a.Puid < b.Puid
+    }
+    return a.Format+a.Version+a.Mime < b.Format+b.Version+b.Mime
+  })
+  return formats
+}
+
+// true if the match identifies no format
+func unidentified(match Matches) bool {
+  id := strings.ToUpper(strings.TrimSpace(match.ID))
+  return id == "" || id == "UNKNOWN"
+}
+
+// Add profiles the formats of a manifest's files as one more package and
+// adds them to the totals across packages. Files with no format identified are
+// listed by name in Unidentified instead of being counted.
+func (r *FormatReport) Add(m *ObjectMetsManifest) {
+  if r.Packages == nil {
+    r.Packages = []PackageFormats{}
+  }
+  if r.Unidentified == nil {
+    r.Unidentified = []UnidentifiedFile{}
+  }
+  profile := PackageFormats{Package: m.StorageLocation}
+  var counts formatCounts
+  for _, file := range m.Manifest.AllFiles() {
+    match := Matches{}
+    if len(file.Matches) > 0 {
+      match = file.Matches[0]
+    }
+    if unidentified(match) {
+      profile.Unidentified++
+      r.Unidentified = append(r.Unidentified, UnidentifiedFile{Package: m.StorageLocation, FileName: file.FileName, Use: file.Use, Bytes: file.FileSize})
+      continue
+    }
+    counts.add(match, 1, file.FileSize)
+  }
+  profile.Formats = counts.list()
+  for _, count := range profile.Formats {
+    total := r.all.add(Matches{ID: count.Puid, Format: count.Format, Version: count.Version, Mime: count.Mime}, count.Files, count.Bytes)
+    total.Packages++
+  }
+  r.Packages = append(r.Packages, profile)
+  r.Formats = r.all.list()
+}
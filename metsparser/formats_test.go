@@ -0,0 +1,60 @@
+package metsparser
+
+import (
+  "reflect"
+  "testing"
+)
+
+func formatFile(name string, use string, size int64, matches ...Matches) FilesMets {
+  return FilesMets{FileName: name, Use: use, FileSize: size, Matches: matches}
+}
+
+func TestFormatReportAdd(t *testing.T) {
+  pdf := Matches{ID: "fmt/18", Format: "Acrobat PDF 1.4", Version: "1.4", Mime: "application/pdf"}
+  tiff := Matches{ID: "fmt/353", Format: "Tagged Image File Format", Mime: "image/tiff"}
+  first := &ObjectMetsManifest{StorageLocation: "pkg-1"}
+  first.Manifest.Files = []FilesMets{
+    formatFile("objects/a.pdf", "original", 10, pdf),
+    formatFile("objects/b.pdf", "original", 20, pdf, Matches{ID: "fmt/276"}),
+    formatFile("objects/c.bin", "original", 3, Matches{ID: "UNKNOWN"}),
+  }
+  first.Manifest.Derivatives = []FilesMets{formatFile("objects/a.tif", "preservation", 5, tiff)}
+  second := &ObjectMetsManifest{StorageLocation: "pkg-2"}
+  second.Manifest.Files = []FilesMets{
+    formatFile("objects/e.pdf", "original", 7, pdf),
+    formatFile("objects/f", "original", 0),
+  }
+
+  var report FormatReport
+  report.Add(first)
+  report.Add(second)
+
+  // the first match counts, identified or not
+  wantPackages := []PackageFormats{
+    {Package: "pkg-1", Unidentified: 1, Formats: []FormatCount{
+      {Puid: "fmt/18", Format: "Acrobat PDF 1.4", Version: "1.4", Mime: "application/pdf", Files: 2, Bytes: 30},
+      {Puid: "fmt/353", Format: "Tagged Image File Format", Mime: "image/tiff", Files: 1, Bytes: 5},
+    }},
+    {Package: "pkg-2", Unidentified: 1, Formats: []FormatCount{
+      {Puid: "fmt/18", Format: "Acrobat PDF 1.4", Version: "1.4", Mime: "application/pdf", Files: 1, Bytes: 7},
+    }},
+  }
+  if !reflect.DeepEqual(report.Packages, wantPackages) {
+    t.Errorf("packages\n%+v\nwant\n%+v", report.Packages, wantPackages)
+  }
+  // totals across packages count the packages each format is found in
+  wantFormats := []FormatCount{
+    {Puid: "fmt/18", Format: "Acrobat PDF 1.4", Version: "1.4", Mime: "application/pdf", Files: 3, Bytes: 37, Packages: 2},
+    {Puid: "fmt/353", Format: "Tagged Image File Format", Mime: "image/tiff", Files: 1, Bytes: 5, Packages: 1},
+  }
+  if !reflect.DeepEqual(report.Formats, wantFormats) {
+    t.Errorf("formats\n%+v\nwant\n%+v", report.Formats, wantFormats)
+  }
+  wantUnidentified := []UnidentifiedFile{
+    {Package: "pkg-1", FileName: "objects/c.bin", Use: "original", Bytes: 3},
+    {Package: "pkg-2", FileName: "objects/f", Use: "original", Bytes: 0},
+  }
+  if !reflect.DeepEqual(report.Unidentified, wantUnidentified) {
+    t.Errorf("unidentified %+v, want %+v", report.Unidentified, wantUnidentified)
+  }
+}
@@ -0,0 +1,95 @@
+package metsparser
+
+import (
+  "strings"
+)
+
+// where a match comes from
+const (
+  BasisPremis = "premis format designation"
+  BasisFits   = "fits identity"
+)
+
+// format identifications of a file: the PREMIS format designation first, as
+// it always was, then one match per FITS identity
+func fileMatches(o PremisObject, events []Events) []Matches {
+  match := Matches{}
+  match.Format = o.Format
+  match.Version = o.Version
+  match.Ns = o.FormatRegistryName
+  match.ID = o.FormatRegistryKey
+  match.Mime = o.Fits.Identity().Mimetype
+  match.Basis = BasisPremis
+  for _, e := range events {
+    if !strings.EqualFold(e.Type, "format identification") {
+      continue
+    }
+    if e.Tool != nil {
+      match.Tool = toolName(e.Tool.Program, e.Tool.Version)
+    }
+    if outcomeSeverity(e.Outcome) != "" {
+      match.Warning = "format identification outcome " + e.Outcome
+    }
+    break
+  }
+  matches := []Matches{match}
+
+  for _, identity := range o.Fits.Identification.Identities {
+    match := Matches{Format: identity.Format, Mime: identity.Mimetype, Basis: BasisFits}
+    if len(identity.Versions) > 0 {
+      match.Version = strings.TrimSpace(identity.Versions[0].Value)
+    }
+    for _, id := range identity.ExternalIdentifiers {
+      if strings.EqualFold(id.Type, "puid") {
+        match.Ns = "PRONOM"
+        match.ID = strings.TrimSpace(id.Value)
+        break
+      }
+    }
+    var tools []string
+    for _, tool := range identity.Tools {
+      tools = append(tools, toolName(tool.Toolname, tool.Toolversion))
+    }
+    if len(tools) == 0 {
+      tools = append(tools, toolName(identity.Toolname, identity.Toolversion))
+    }
+    match.Tool = strings.Join(tools, ", ")
+    if strings.EqualFold(o.Fits.Identification.Status, "CONFLICT") {
+      match.Warning = "FITS tools disagree on this file"
+    }
+    matches = append(matches, match)
+  }
+  return matches
+}
+
+// true if the matches name different formats, or different MIME types
+func identificationConflicts(matches []Matches, fits Fits) (bool, bool) {
+  puids := make(map[string]bool)
+  fitsFormats := make(map[string]bool)
+  mimes := make(map[string]bool)
+  for _, m := range matches {
+    if m.ID != "" {
+      puids[strings.ToLower(m.ID)] = true
+    }
+    if m.Basis == BasisFits && m.Format != "" {
+      fitsFormats[strings.ToLower(m.Format)] = true
+    }
+    if mime := normalizeMime(m.Mime); mime != "" {
+      mimes[mime] = true
+    }
+  }
+  formatConflict := len(puids) > 1 || len(fitsFormats) > 1 || strings.EqualFold(fits.Identification.Status, "CONFLICT")
+  return formatConflict, len(mimes) > 1
+}
+
+// MIME type without parameters, lowercased
+func normalizeMime(mime string) string {
+  if i := strings.IndexByte(mime, ';'); i >= 0 {
+    mime = mime[:i]
+  }
+  return strings.ToLower(strings.TrimSpace(mime))
+}
+
+func toolName(program string, version string) string {
+  return strings.TrimSpace(program + " " + version)
+}
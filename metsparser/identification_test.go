@@ -0,0 +1,82 @@
+package metsparser
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestFileMatches(t *testing.T) {
+  o := PremisObject{Format: "Acrobat PDF 1.4", Version: "1.4", FormatRegistryName: "PRONOM", FormatRegistryKey: "fmt/18"}
+  o.Fits.Identification = FitsIdentification{
+    Status: "CONFLICT",
+    Identities: []Identity{
+      {
+        Format:              "Portable Document Format",
+        Mimetype:            "application/pdf",
+        Tools:               []FitsTool{{Toolname: "Jhove", Toolversion: "1.20.1"}, {Toolname: "Droid", Toolversion: "6.4"}},
+        Versions:            []FitsToolValue{{Value: " 1.4 "}},
+        ExternalIdentifiers: []FitsExternalIdentifier{{Type: "mime", Value: "application/pdf"}, {Type: "PUID", Value: " fmt/18 "}},
+      },
+      {Format: "Plain text", Mimetype: "text/plain; charset=US-ASCII", Toolname: "file utility", Toolversion: "5.04"},
+    },
+  }
+  events := []Events{
+    {Type: "virus check", Outcome: "Pass", Tool: &EventTool{Program: "ClamAV"}},
+    {Type: "format identification", Outcome: "Positive", Tool: &EventTool{Program: "Siegfried", Version: "1.9.1"}},
+  }
+
+  conflict := "FITS tools disagree on this file"
+  want := []Matches{
+    {Ns: "PRONOM", ID: "fmt/18", Format: "Acrobat PDF 1.4", Version: "1.4", Mime: "application/pdf", Basis: BasisPremis, Tool: "Siegfried 1.9.1"},
+    {Ns: "PRONOM", ID: "fmt/18", Format: "Portable Document Format", Version: "1.4", Mime: "application/pdf", Basis: BasisFits, Tool: "Jhove 1.20.1, Droid 6.4", Warning: conflict},
+    {Format: "Plain text", Mime: "text/plain; charset=US-ASCII", Basis: BasisFits, Tool: "file utility 5.04", Warning: conflict},
+  }
+  got := fileMatches(o, events)
+  if !reflect.DeepEqual(got, want) {
+    t.Errorf("matches\n%+v\nwant\n%+v", got, want)
+  }
+
+  // without FITS only the PREMIS match, warned of a failed identification
+  got = fileMatches(PremisObject{Format: "Unknown"}, []Events{{Type: "Format identification", Outcome: "Negative"}})
+  want = []Matches{{Format: "Unknown", Basis: BasisPremis, Warning: "format identification outcome Negative"}}
+  if !reflect.DeepEqual(got, want) {
+    t.Errorf("matches %+v, want %+v", got, want)
+  }
+}
+
+func TestIdentificationConflicts(t *testing.T) {
+  premis := Matches{ID: "fmt/18", Format: "Acrobat PDF 1.4", Mime: "application/pdf", Basis: BasisPremis}
+  tests := []struct {
+    name    string
+    matches []Matches
+    status  string
+    format  bool
+    mime    bool
+  }{
+    {"premis only", []Matches{premis}, "", false, false},
+    {"agreeing identities", []Matches{premis,
+      {ID: "FMT/18", Format: "Portable Document Format", Mime: "application/pdf; version=1.4", Basis: BasisFits},
+      {Format: "portable document format", Mime: "Application/PDF", Basis: BasisFits},
+    }, "SINGLE_RESULT", false, false},
+    {"puid conflict", []Matches{premis,
+      {ID: "fmt/276", Format: "Portable Document Format", Mime: "application/pdf", Basis: BasisFits},
+    }, "", true, false},
+    {"fits formats differ", []Matches{premis,
+      {Format: "Portable Document Format", Basis: BasisFits},
+      {Format: "Adobe Illustrator", Basis: BasisFits},
+    }, "", true, false},
+    {"mime conflict", []Matches{premis,
+      {ID: "fmt/18", Format: "Portable Document Format", Mime: "text/plain", Basis: BasisFits},
+    }, "", false, true},
+    {"fits status conflict", []Matches{premis}, "conflict", true, false},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      fits := Fits{Identification: FitsIdentification{Status: tt.status}}
+      format, mime := identificationConflicts(tt.matches, fits)
+      if format != tt.format || mime != tt.mime {
+        t.Errorf("format conflict %v, mime conflict %v, want %v, %v", format, mime, tt.format, tt.mime)
+      }
+    })
+  }
+}
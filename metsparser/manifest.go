@@ -0,0 +1,328 @@
+package metsparser
+
+import (
+  "encoding/xml"
+)
+
+// ********* JSON Structs *********
+
+// New
+type ObjectMetsManifest struct { // TODO Data structure name may change
+	Title               string           `json:"title"`
+	JiraTicketNumber    string           `json:"jira_ticket_number"`
+	DepartmentOrLibrary string           `json:"department_or_library"`
+	CollectionCall      string           `json:"collection_call"`
+	DepositorName       string           `json:"depositor_name"`
+	BaggingDate         string           `json:"bagging_date"`
+	Description         string           `json:"description"`
+	TransferMetadata    map[string]string `json:"transfer_metadata"`
+	SfErrors            string           `json:"sf_errors"`
+	Status              string           `json:"status"`
+	NewTarTechMD        NewTarTechMd     `json:"tar_techMD"`
+	ManifestSha256      string           `json:"manifest_sha256"`
+	ManifestMd5         string           `json:"manifest_md5"`
+	Manifest            ManifestMets     `json:"manifest"`
+	Rights              []Rights         `json:"rights"`
+	AccessRestricted    bool             `json:"access_restricted"`
+	EmbargoEndDate      string           `json:"embargo_end_date"`
+	StorageLocation     string           `json:"storage_location"`
+	FileCount           int64            `json:"file_count"`
+	TotalSize           int64            `json:"total_size"`
+	Warnings            []string         `json:"warnings"`
+	Agents              []Agents         `json:"agents"`
+	Tools               []ToolUsage      `json:"tools"`
+	FormatRisk          *RiskSummary     `json:"format_risk"`
+	Quality             QualitySummary   `json:"quality"`
+	SchemaVersion       string           `json:"schema_version"`
+}
+
+// NewTarTechMd represents the Tar Tech MD used in Object Metadata
+type NewTarTechMd struct {
+	Siegfried   string        `json:"siegfried"`
+	Scandate    string        `json:"scandate"`
+	Signature   string        `json:"signature"`
+	Created     string        `json:"created"`
+	Identifiers []Identifiers `json:"identifiers"`
+	Files       []Files       `json:"files"`
+}
+
+// Files represents the Manifest Files Array Structure
+type Files struct {
+	FileName string        `json:"filename"`
+	FileSize int64         `json:"filesize"`
+	Modified string        `json:"modified"`
+	Errors   string        `json:"errors"`
+	Md5      string        `json:"md5"`
+  Sha256   string        `json:"sha256"`
+	Matches  []Matches     `json:"matches"`
+}
+
+// Identifiers represents the Identifiers Structure used in manifest file
+type Identifiers struct {
+	Name    string `json:"name"`
+	Details string `json:"details"`
+}
+
+// Matches represents Matches Structure
+type Matches struct {
+	Ns      string `json:"ns"`
+	ID      string `json:"id"`
+	Format  string `json:"format"`
+	Version string `json:"version"`
+	Mime    string `json:"mime"`
+	Basis   string `json:"basis"`
+	Warning string `json:"warning"`
+	Tool    string `json:"tool"`
+}
+
+// New
+type ManifestMets struct {
+	Siegfried   string        `json:"siegfried"`
+	Scandate    string        `json:"scandate"`
+	Signature   string        `json:"signature"`
+	Created     string        `json:"created"`
+	Identifiers []Identifiers `json:"identifiers"`
+	Files       []FilesMets   `json:"files"`
+	Derivatives []FilesMets   `json:"derivatives"`
+	Supporting  []FilesMets   `json:"supporting_documentation"`
+}
+
+// New
+type FilesMets struct {
+	FileName             string                 `json:"filename"`
+	Use                  string                 `json:"use"`
+	Uuid                 string                 `json:"uuid"`
+	FileSize             int64                  `json:"filesize"`
+	Modified             string                 `json:"modified"`
+	Errors               string                 `json:"errors"`
+	Md5                  string                 `json:"md5"`
+  Sha256               string                 `json:"sha256"`
+	Matches              []Matches              `json:"matches"`
+	FormatConflict       bool                   `json:"format_conflict"`
+	MimeConflict         bool                   `json:"mime_conflict"`
+	FormatRisk           *FormatRisk            `json:"format_risk"`
+	QualityWarnings      []QualityWarning       `json:"quality_warnings"`
+	DescriptiveMD        DescriptiveMD          `json:"descriptiveMD"`
+	DescriptiveMDHistory []DescriptiveMDVersion `json:"descriptiveMD_history,omitempty"`
+	Rights               []Rights               `json:"rights"`
+	AccessRestricted     bool                   `json:"access_restricted"`
+	EmbargoEndDate       string                 `json:"embargo_end_date"`
+	DerivedFiles         []RelatedFile          `json:"derived_files"`
+	EventErrors          []EventError           `json:"event_errors"`
+	Validation           []ValidationResult     `json:"validation"`
+	SourceFiles          []RelatedFile          `json:"source_files"`
+}
+
+// New: an event of the file that failed or passed with warnings
+type EventError struct {
+  Uuid     string `json:"uuid"`
+  Type     string `json:"type"`
+  Outcome  string `json:"outcome"`
+  Note     string `json:"note"`
+  Severity string `json:"severity"`
+}
+
+// New: outcome of a validation event, such as JHOVE's, in structured form
+type ValidationResult struct {
+  EventUuid     string   `json:"event_uuid"`
+  Tool          string   `json:"tool"`
+  ToolVersion   string   `json:"tool_version"`
+  Format        string   `json:"format"`
+  FormatVersion string   `json:"format_version"`
+  WellFormed    *bool    `json:"well_formed"`
+  Valid         *bool    `json:"valid"`
+  Result        string   `json:"result"`
+  Outcome       string   `json:"outcome"`
+  Messages      []string `json:"messages"`
+}
+
+// New: a tool and version that processed files of the package, from event
+// details and FITS identities
+type ToolUsage struct {
+  Program    string   `json:"program"`
+  Version    string   `json:"version"`
+  Sources    []string `json:"sources"`
+  EventTypes []string `json:"event_types"`
+  Files      int64    `json:"files"`
+  Packages   int64    `json:"packages,omitempty"`
+}
+
+// New: preservation risk of a file's format under the local format policy
+type FormatRisk struct {
+  Puid         string   `json:"puid"`
+  Risk         string   `json:"risk"`
+  Preferred    []string `json:"preferred"`
+  Note         string   `json:"note"`
+  Unidentified bool     `json:"unidentified"`
+}
+
+// New: format risks of a package, flagged when it holds high-risk or
+// unidentified formats
+type RiskSummary struct {
+  HighRisk          bool             `json:"high_risk"`
+  Unidentified      bool             `json:"unidentified"`
+  HighRiskFiles     int64            `json:"high_risk_files"`
+  UnidentifiedFiles int64            `json:"unidentified_files"`
+  HighRiskPuids     []string         `json:"high_risk_puids"`
+  Levels            map[string]int64 `json:"levels"`
+}
+
+// New: a suspicious file: wrong extension, empty, unidentified or sized
+// differently by METS and FITS
+type QualityWarning struct {
+  Code    string `json:"code"`
+  Message string `json:"message"`
+}
+
+// New: quality warnings of a package, counted per code, with the files
+// having any
+type QualitySummary struct {
+  FilesWithWarnings int64            `json:"files_with_warnings"`
+  Warnings          map[string]int64 `json:"warnings"`
+  Files             []string         `json:"files"`
+}
+
+// New: a file normalized from this one, or the one it was normalized from
+type RelatedFile struct {
+  Uuid     string  `json:"uuid"`
+  FileName string  `json:"filename"`
+  Use      string  `json:"use"`
+  Event    *Events `json:"event"`
+}
+
+type DescriptiveMD struct {
+  XMLName               xml.Name `xml:"dublincore" json:"-"`
+  Identifier            string   `xml:"identifier" json:"identifier"`
+  Title                 string   `xml:"title" json:"title"`
+  Creator               string   `xml:"creator" json:"creator"`
+  Date                  string   `xml:"date" json:"date"`
+  Type                  string   `xml:"type" json:"type"`
+  Format                string   `xml:"format" json:"format"`
+  LanguageArr           []string `xml:"language" json:"-"`
+  Language              string   `json:"language"`
+  Contributor           string   `xml:"contributor" json:"contributor"`
+  Provenance            string   `xml:"provenance" json:"provenance"`
+  SubjectArr            []string `xml:"subject" json:"-"`
+  Subject               string   `json:"subject"`
+  Description           string   `xml:"description" json:"description"`
+  Publisher             string   `xml:"publisher" json:"publisher"`
+  Source                string   `xml:"source" json:"source"`
+  Relation              string   `xml:"relation" json:"relation"`
+  Coverage              string   `xml:"converge" json:"converge"`
+  Rights                string   `xml:"rights" json:"rights"`
+  IsPartOf              string   `xml:"isPartOf,omitempty" json:"isPartOf,omitempty"`
+  Abstract              string   `xml:"abstract,omitempty" json:"abstract,,omitempty"`
+  AccessRights          string   `xml:"accessRights,omitempty" json:"accessRights,omitempty"`
+  AccrualMethod         string   `xml:"accrualMethod,omitempty" json:"accrualMethod,omitempty"`
+  AccrualPeriodicity    string   `xml:"accrualPeriodicity,omitempty" json:"accrualPeriodicity,omitempty"`
+  AccrualPolicy         string   `xml:"accrualPolicy,omitempty" json:"accrualPolicy,omitempty"`
+  Alternative           string   `xml:"alternative,omitempty" json:"alternative,omitempty"`
+  Audience              string   `xml:"audience,omitempty" json:"audience,omitempty"`
+  Available             string   `xml:"available,omitempty" json:"available,omitempty"`
+  BibliographicCitation string   `xml:"bibliographicCitation,omitempty" json:"bibliographicCitation,omitempty"`
+  ConformsTo            string   `xml:"conformsTo,omitempty" json:"conformsTo,omitempty"`
+  Created               string   `xml:"created,omitempty" json:"created,omitempty"`
+  DateAccepted          string   `xml:"dateAccepted,omitempty" json:"dateAccepted,omitempty"`
+  DateCopyrighted       string   `xml:"dateCopyrighted,omitempty" json:"dateCopyrighted,omitempty"`
+  DateSubmitted         string   `xml:"dateSubmitted,omitempty" json:"dateSubmitted,omitempty"`
+  EducationLevel        string   `xml:"educationLevel,omitempty" json:"educationLevel,omitempty"`
+  Extent                string   `xml:"extent,omitempty" json:"extent,omitempty"`
+  HasFormat             string   `xml:"hasFormat,omitempty" json:"hasFormat,omitempty"`
+  HasPart               string   `xml:"hasPart,omitempty" json:"hasPart,omitempty"`
+  HasVersion            string   `xml:"hasVersion,omitempty" json:"hasVersion,omitempty"`
+  InstructionalMethod   string   `xml:"instructionalMethod,omitempty" json:"instructionalMethod,omitempty"`
+  IsFormatOf            string   `xml:"isFormatOf,omitempty" json:"isFormatOf,omitempty"`
+  IsReferencedBy        string   `xml:"isReferencedBy,omitempty" json:"isReferencedBy,omitempty"`
+  IsReplacedBy          string   `xml:"isReplacedBy,omitempty" json:"isReplacedBy,omitempty"`
+  IsRequiredBy          string   `xml:"isRequiredBy,omitempty" json:"isRequiredBy,omitempty"`
+  Issued                string   `xml:"issued,omitempty" json:"issued,omitempty"`
+  IsVersionOf           string   `xml:"isVersionOf,omitempty" json:"isVersionOf,omitempty"`
+  License               string   `xml:"license,omitempty" json:"license,omitempty"`
+  Mediator              string   `xml:"mediator,omitempty" json:"mediator,omitempty"`
+  Modified              string   `xml:"modified,omitempty" json:"modified,omitempty"`
+  References            string   `xml:"references,omitempty" json:"references,omitempty"`
+  Replaces              string   `xml:"replaces,omitempty" json:"replaces,omitempty"`
+  Requires              string   `xml:"requires,omitempty" json:"requires,omitempty"`
+  RightsHolder          string   `xml:"rightsHolder,omitempty" json:"rightsHolder,omitempty"`
+  Spatial               string   `xml:"spatial,omitempty" json:"spatial,omitempty"`
+  TableOfContents       string   `xml:"tableOfContents,omitempty" json:"tableOfContents,omitempty"`
+  Temporal              string   `xml:"temporal,omitempty" json:"temporal,omitempty"`
+  Valid                 string   `xml:"valid,omitempty" json:"valid,omitempty"`
+  Events                []Events `json:"events"`
+  Agents                []Agents `json:"agents,omitempty"`
+  AgentIds              []string `json:"agent_ids,omitempty"`
+}
+
+// New: one version of a file's descriptive metadata after metadata reingest
+type DescriptiveMDVersion struct {
+  ID            string        `json:"id"`
+  Status        string        `json:"status"`
+  Created       string        `json:"created"`
+  DescriptiveMD DescriptiveMD `json:"descriptiveMD"`
+}
+
+// New: Premis events
+type Events struct {
+  Uuid           string              `json:"uuid"`
+  Type           string              `json:"type"`
+  DateTime       string              `json:"datetime"`
+  Outcome        string              `json:"outcome"`
+  Detail         string              `json:"detail"`
+  DetailNote     string              `json:"detail_note"`
+  LinkingAgents  []LinkingIdentifier `json:"linking_agents"`
+  LinkingObjects []LinkingIdentifier `json:"linking_objects"`
+  Tool           *EventTool          `json:"tool"`
+}
+
+// New: the program that performed an event, read from the event detail
+type EventTool struct {
+  Program string            `json:"program"`
+  Version string            `json:"version"`
+  Params  map[string]string `json:"params"`
+}
+
+// New: reference from an event to an agent or object, by PREMIS identifier
+type LinkingIdentifier struct {
+  IdentifierType  string   `json:"identifier_type"`
+  IdentifierValue string   `json:"identifier_value"`
+  Roles           []string `json:"roles"`
+  AgentId         string   `json:"agent_id,omitempty"`
+}
+
+// New: Premis rights statement, with the fields of its basis
+type Rights struct {
+  Uuid                    string          `json:"uuid"`
+  Basis                   string          `json:"basis"`
+  Status                  string          `json:"status"`
+  Jurisdiction            string          `json:"jurisdiction"`
+  DeterminationDate       string          `json:"determination_date"`
+  Citation                string          `json:"citation"`
+  Terms                   string          `json:"terms"`
+  DocumentationIdentifier string          `json:"documentation_identifier"`
+  StartDate               string          `json:"start_date"`
+  EndDate                 string          `json:"end_date"`
+  Note                    string          `json:"note"`
+  Granted                 []RightsGranted `json:"granted"`
+}
+
+// New: Premis rights granted
+type RightsGranted struct {
+  Act                  string `json:"act"`
+  Restriction          string `json:"restriction"`
+  StartDate            string `json:"start_date"`
+  EndDate              string `json:"end_date"`
+  RestrictionStartDate string `json:"restriction_start_date,omitempty"`
+  RestrictionEndDate   string `json:"restriction_end_date,omitempty"`
+  Note                 string `json:"note"`
+}
+
+// New: Premis agents
+type Agents struct {
+  ID               string `json:"id,omitempty"`
+  IdentifierType   string `json:"identifier_type"`
+  IdentifierValue  string `json:"identifier_value"`
+  Name             string `json:"name"`
+  Type             string `json:"type"`
+}
+
+
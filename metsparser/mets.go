@@ -0,0 +1,357 @@
+package metsparser
+
+import (
+  "encoding/xml"
+  "io"
+)
+
+// ********* XML Structs *********
+type Mets struct {
+  XMLName        xml.Name         `xml:"mets"`
+  Header struct {
+    CreateDate string `xml:"CREATEDATE,attr"`
+    ModifyDate string `xml:"LASTMODDATE,attr"`
+  } `xml:"metsHdr"`
+  // Header         MetsHeader       `xml:"metsHdr"`
+  DescriptiveSec []DescriptiveSec `xml:"dmdSec"`
+  AdminSec       []AdminSec       `xml:"amdSec"`
+  FileSec        FileSec          `xml:"fileSec"`
+  StructMap      []StructMap      `xml:"structMap"`
+}
+
+// dmdSec
+type DescriptiveSec struct {
+  XMLName    xml.Name     `xml:"dmdSec"`
+  ID         string       `xml:"ID,attr"`
+  Status     string       `xml:"STATUS,attr"` // original, updated or deleted after a metadata reingest
+  Created    string       `xml:"CREATED,attr"`
+  Dmd        Dmd          `xml:"mdWrap"`
+  DigiProvMD []DigiProvMD `xml:"digiprovMD"`
+}
+
+type Dmd struct {
+  XMLName      xml.Name      `xml:"mdWrap"`
+  Mdtype       string        `xml:"MDTYPE,attr"`
+  PremisObject PremisObject  `xml:"xmlData>object"`
+  DublinCoreMD DescriptiveMD `xml:"xmlData>dublincore"`
+}
+
+// amdSec
+type AdminSec struct {
+  XMLName     xml.Name     `xml:"amdSec"`
+  ID          string       `xml:"ID,attr"`
+  TechnicalMD TechnicalMD  `xml:"techMD"`
+  DigiProvMD  []DigiProvMD `xml:"digiprovMD"`
+  RightsMD    []RightsMD   `xml:"rightsMD"`
+  SourceMD    SourceMD     `xml:"sourceMD"`
+}
+
+// amdSec > SourceMD
+type SourceMD struct {
+  XMLName                   xml.Name `xml:"sourceMD"`
+  ID                        string   `xml:"ID,attr"`
+  Payload                   string   `xml:"mdWrap>xmlData>transfer_metadata>Payload-Oxum"`
+  BagCount                  string   `xml:"mdWrap>xmlData>transfer_metadata>Bag-Count"`
+  ContactName               string   `xml:"mdWrap>xmlData>transfer_metadata>Contact-Name"`
+  ContactEmail              string   `xml:"mdWrap>xmlData>transfer_metadata>Contact-Email"`
+  BagSize                   string   `xml:"mdWrap>xmlData>transfer_metadata>Bag-Size"`
+  BaggingDate               string   `xml:"mdWrap>xmlData>transfer_metadata>Bagging-Date"`
+  SourceOrganization        string   `xml:"mdWrap>xmlData>transfer_metadata>Source-Organization"`
+  ExternalDescription       string   `xml:"mdWrap>xmlData>transfer_metadata>External-Description"`
+  ExternalIdentifier        string   `xml:"mdWrap>xmlData>transfer_metadata>External-Identifier"`
+  BagGroupIdentifier        string   `xml:"mdWrap>xmlData>transfer_metadata>Bag-Group-Identifier"`
+  InternalSenderIdentifier  string   `xml:"mdWrap>xmlData>transfer_metadata>Internal-Sender-Identifier"`
+  InternalSenderDescription string   `xml:"mdWrap>xmlData>transfer_metadata>Internal-Sender-Description"`
+  Raw                       string   `xml:",innerxml"`
+}
+
+// amdSec > SourceMD > transfer_metadata > any bag-info field
+type BagInfoField struct {
+  XMLName xml.Name
+  Value   string `xml:",chardata"`
+}
+
+// BagInfo returns every bag-info field of the transfer, in document order
+func (s SourceMD) BagInfo() ([]BagInfoField, error) {
+  if s.Raw == "" {
+    return nil, nil
+  }
+  bag := struct {
+    TransferMetadata struct {
+      Fields []BagInfoField `xml:",any"`
+    } `xml:"mdWrap>xmlData>transfer_metadata"`
+  }{}
+  err := xml.Unmarshal([]byte("<sourceMD>"+s.Raw+"</sourceMD>"), &bag)
+  if err != nil {
+    return nil, err
+  }
+  return bag.TransferMetadata.Fields, nil
+}
+
+// amdSec > TechnicalMD
+type TechnicalMD struct {
+  XMLName      xml.Name     `xml:"techMD"`
+  ID           string       `xml:"ID,attr"`
+  PremisObject PremisObject `xml:"mdWrap>xmlData>object"`
+}
+
+// mets > []structmap
+type StructMap struct {
+  XMLName xml.Name `xml:"structMap"`
+  ID      string   `xml:"ID,attr"`
+  Label   string   `xml:"LABEL,attr"`
+  Type    string   `xml:"TYPE,attr"`
+  Parent  Div      `xml:"div"`
+}
+
+// structmap > div (File item Div)
+type Div struct {
+  XMLName xml.Name     `xml:"div"`
+  Label    string      `xml:"LABEL,attr"`
+  Type     string      `xml:"TYPE,attr"`
+  Dmdid    string      `xml:"DMDID,attr"`
+  Admid    string      `xml:"ADMID,attr"`
+  File     FilePointer `xml:"fptr"`
+  Children []Div       `xml:"div"`
+}
+
+// structmap > div (item) > fileptr
+type FilePointer struct {
+  XMLName xml.Name `xml:"fptr"`
+  Fileid  string   `xml:"FILEID,attr"`
+}
+
+// amdSec > digiprov
+type DigiProvMD struct {
+  XMLName xml.Name `xml:"digiprovMD"`
+  ID      string   `xml:"ID,attr"`
+  Mdtype  string   `xml:"MDTYPE,attr"`
+  Premis  Premis   `xml:"mdWrap"`
+}
+
+// amdSec > digiprov > PremisAgent | PremisEvent
+type Premis struct {
+  Mdtype      string      `xml:"MDTYPE,attr"`
+  PremisAgent PremisAgent `xml:"xmlData>agent"`
+  PremisEvent PremisEvent `xml:"xmlData>event"`
+}
+
+// amdSec > digiprov > PremisEvent
+type PremisEvent struct {
+  XMLName              xml.Name              `xml:"event"`
+  EventIdentifierValue string                `xml:"eventIdentifier>eventIdentifierValue"`
+  EventType            string                `xml:"eventType"`
+  EventDate            string                `xml:"eventDateTime"`
+  // EventDetail          string   `xml:"eventDetail"`  // FRDR METS different structure
+  EventDetail          string                `xml:"eventDetailInformation>eventDetail"`
+  EventOutcome         string                `xml:"eventOutcomeInformation>eventOutcome"`
+  EventOutcomeNote     string                `xml:"eventOutcomeInformation>eventOutcomeDetail>eventOutcomeDetailNote"`
+  LinkingAgents        []PremisLinkingAgent  `xml:"linkingAgentIdentifier"`
+  LinkingObjects       []PremisLinkingObject `xml:"linkingObjectIdentifier"`
+}
+type PremisLinkingAgent struct {
+  Type  string   `xml:"linkingAgentIdentifierType"`
+  Value string   `xml:"linkingAgentIdentifierValue"`
+  Roles []string `xml:"linkingAgentRole"`
+}
+type PremisLinkingObject struct {
+  Type  string   `xml:"linkingObjectIdentifierType"`
+  Value string   `xml:"linkingObjectIdentifierValue"`
+  Roles []string `xml:"linkingObjectRole"`
+}
+
+// amdSec > digiprov > PremisEvent
+type PremisAgent struct {
+  XMLName              xml.Name `xml:"agent"`
+  AgentIdentifierType  string   `xml:"agentIdentifier>agentIdentifierType"`
+  AgentIdentifierValue string   `xml:"agentIdentifier>agentIdentifierValue"`
+  AgentName            string   `xml:"agentName"`
+  AgentType            string   `xml:"agentType"`
+}
+
+// amdSec > rightsmd
+type RightsMD struct {
+  XMLName         xml.Name              `xml:"rightsMD"`
+  ID              string                `xml:"ID,attr"`
+  RightsStatement PremisRightsStatement `xml:"mdWrap>xmlData>rightsStatement"`
+}
+
+// amdSec > rightsmd > PremisRightsStatement
+type PremisRightsStatement struct {
+  IdentifierType          string                       `xml:"rightsStatementIdentifier>rightsStatementIdentifierType"`
+  IdentifierValue         string                       `xml:"rightsStatementIdentifier>rightsStatementIdentifierValue"`
+  RightsBasis             string                       `xml:"rightsBasis"`
+  CopyrightInformation    PremisCopyrightInformation   `xml:"copyrightInformation"`
+  LicenseInformation      PremisLicenseInformation     `xml:"licenseInformation"`
+  StatuteInformation      []PremisStatuteInformation   `xml:"statuteInformation"`
+  OtherRightsInformation  PremisOtherRightsInformation `xml:"otherRightsInformation"`
+  RightsGranted           []PremisRightsGranted        `xml:"rightsGranted"`
+  LinkingObjectIdentifier []string                     `xml:"linkingObjectIdentifier>linkingObjectIdentifierValue"`
+  LinkingAgentIdentifier  []string                     `xml:"linkingAgentIdentifier>linkingAgentIdentifierValue"`
+}
+
+// amdSec > rightsmd > PremisRightsStatement > copyrightInformation
+type PremisCopyrightInformation struct {
+  Status                  string   `xml:"copyrightStatus"`
+  Jurisdiction            string   `xml:"copyrightJurisdiction"`
+  DeterminationDate       string   `xml:"copyrightStatusDeterminationDate"`
+  Note                    []string `xml:"copyrightNote"`
+  DocumentationIdentifier []string `xml:"copyrightDocumentationIdentifier>copyrightDocumentationIdentifierValue"`
+  StartDate               string   `xml:"copyrightApplicableDates>startDate"`
+  EndDate                 string   `xml:"copyrightApplicableDates>endDate"`
+}
+
+// amdSec > rightsmd > PremisRightsStatement > licenseInformation
+type PremisLicenseInformation struct {
+  Terms                   string   `xml:"licenseTerms"`
+  Note                    []string `xml:"licenseNote"`
+  DocumentationIdentifier []string `xml:"licenseDocumentationIdentifier>licenseDocumentationIdentifierValue"`
+  StartDate               string   `xml:"licenseApplicableDates>startDate"`
+  EndDate                 string   `xml:"licenseApplicableDates>endDate"`
+}
+
+// amdSec > rightsmd > PremisRightsStatement > statuteInformation
+type PremisStatuteInformation struct {
+  Jurisdiction            string   `xml:"statuteJurisdiction"`
+  Citation                string   `xml:"statuteCitation"`
+  DeterminationDate       string   `xml:"statuteInformationDeterminationDate"`
+  Note                    []string `xml:"statuteNote"`
+  DocumentationIdentifier []string `xml:"statuteDocumentationIdentifier>statuteDocumentationIdentifierValue"`
+  StartDate               string   `xml:"statuteApplicableDates>startDate"`
+  EndDate                 string   `xml:"statuteApplicableDates>endDate"`
+}
+
+// amdSec > rightsmd > PremisRightsStatement > otherRightsInformation
+type PremisOtherRightsInformation struct {
+  Basis                   string   `xml:"otherRightsBasis"`
+  Note                    []string `xml:"otherRightsNote"`
+  DocumentationIdentifier []string `xml:"otherRightsDocumentationIdentifier>otherRightsDocumentationIdentifierValue"`
+  StartDate               string   `xml:"otherRightsApplicableDates>startDate"`
+  EndDate                 string   `xml:"otherRightsApplicableDates>endDate"`
+}
+
+// amdSec > rightsmd > PremisRightsStatement > rightsGranted
+type PremisRightsGranted struct {
+  Act                  string   `xml:"act"`
+  Restriction          []string `xml:"restriction"`
+  GrantStartDate       string   `xml:"termOfGrant>startDate"`
+  GrantEndDate         string   `xml:"termOfGrant>endDate"`
+  RestrictionStartDate string   `xml:"termOfRestriction>startDate"`
+  RestrictionEndDate   string   `xml:"termOfRestriction>endDate"`
+  Note                 []string `xml:"rightsGrantedNote"`
+}
+
+// amdSec > techMd > PremisObject
+type PremisObject struct {
+  ObjectName         string               `xml:"originalName"`
+  Uuid               []string             `xml:"objectIdentifier>objectIdentifierValue"`
+  Hashtype           string               `xml:"objectCharacteristics>fixity>messageDigestAlgorithm"`
+  Hashvalue          string               `xml:"objectCharacteristics>fixity>messageDigest"`
+  Bytes              string               `xml:"objectCharacteristics>size"`
+  Format             string               `xml:"objectCharacteristics>format>formatDesignation>formatName"`
+  Version            string               `xml:"objectCharacteristics>format>formatDesignation>formatVersion"`
+  FormatRegistryName string               `xml:"objectCharacteristics>format>formatRegistry>formatRegistryName"`
+  FormatRegistryKey  string               `xml:"objectCharacteristics>format>formatRegistry>formatRegistryKey"`
+  ModifiedDate       string               `xml:"objectCharacteristics>creatingApplication>dateCreatedByApplication"`
+  Fits               Fits                 `xml:"objectCharacteristics>objectCharacteristicsExtension>fits"`
+  Relationships      []PremisRelationship `xml:"relationship"`
+}
+type PremisRelationship struct {
+  Type               string `xml:"relationshipType"`
+  SubType            string `xml:"relationshipSubType"`
+  RelatedObjectValue string `xml:"relatedObjectIdentifier>relatedObjectIdentifierValue"`
+  RelatedEventValue  string `xml:"relatedEventIdentifier>relatedEventIdentifierValue"`
+}
+
+// amdSec > techMd > PremisObject > Fits
+type Fits struct {
+  XMLName          xml.Name           `xml:"fits"`
+  ModifiedUnixtime string             `xml:"fits>fileinfo>fslastmodified"`
+  Md5              string             `xml:"fileinfo>md5checksum"`
+  Filepath         string             `xml:"fileinfo>filepath"`
+  Filename         string             `xml:"fileinfo>filename"`
+  Size             string             `xml:"fileinfo>size"`
+  Identification   FitsIdentification `xml:"identification"`
+}
+
+// Identity returns the first identity FITS reports
+func (f Fits) Identity() Identity {
+  if len(f.Identification.Identities) == 0 {
+    return Identity{}
+  }
+  return f.Identification.Identities[0]
+}
+
+// amdSec > techMd > PremisObject > Fits > Identification
+type FitsIdentification struct {
+  Status     string     `xml:"status,attr"`
+  Identities []Identity `xml:"identity"`
+}
+
+// amdSec > techMd > PremisObject > Fits > Identity
+type Identity struct {
+  XMLName             xml.Name                 `xml:"identity"`
+  Format              string                   `xml:"format,attr"`
+  Mimetype            string                   `xml:"mimetype,attr"`
+  Toolname            string                   `xml:"toolname,attr"`
+  Toolversion         string                   `xml:"toolversion,attr"`
+  Tools               []FitsTool               `xml:"tool"`
+  Versions            []FitsToolValue          `xml:"version"`
+  ExternalIdentifiers []FitsExternalIdentifier `xml:"externalIdentifier"`
+}
+type FitsTool struct {
+  Toolname    string `xml:"toolname,attr"`
+  Toolversion string `xml:"toolversion,attr"`
+}
+type FitsToolValue struct {
+  Toolname    string `xml:"toolname,attr"`
+  Toolversion string `xml:"toolversion,attr"`
+  Value       string `xml:",chardata"`
+}
+type FitsExternalIdentifier struct {
+  Toolname    string `xml:"toolname,attr"`
+  Toolversion string `xml:"toolversion,attr"`
+  Type        string `xml:"type,attr"`
+  Value       string `xml:",chardata"`
+}
+
+// mets > filesec
+type FileSec struct {
+  XMLName xml.Name  `xml:"fileSec"`
+  FileGrp []FileGrp `xml:"fileGrp"`
+}
+
+// mets > filesec > filegrp
+type FileGrp struct {
+  XMLName  xml.Name `xml:"fileGrp"`
+  FileType string   `xml:"USE,attr"`
+  Files    []File   `xml:"file"`
+}
+
+type File struct {
+  XMLName xml.Name `xml:"file"`
+  Admid   string   `xml:"ADMID,attr"`
+  ID      string   `xml:"ID,attr"`
+  FileLocation struct {
+    Location string `xml:"href,attr"`
+  } `xml:"FLocat"`
+}
+
+// Associate object to corresponding mets metadata
+type FileMapped struct {
+  Admid string
+  Dmdid []string
+  Name string
+  Use string
+}
+
+// Parse decodes a METS document into the Mets struct
+func Parse(r io.Reader) (*Mets, error) {
+  mets := Mets{}
+  // deserialization, transform XML to go object that can be processed
+  err := xml.NewDecoder(r).Decode(&mets)
+  if err != nil {
+    return nil, &ParseError{Err: err}
+  }
+  return &mets, nil
+}
@@ -0,0 +1,405 @@
+package metsparser
+
+import (
+  "encoding/json"
+  "fmt"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+// Migration upgrades a manifest, decoded as generic JSON, from one schema
+// version to the next. Apply returns the fields it could only fill with
+// placeholder values, as JSON paths with "[]" standing for every array item.
+type Migration struct {
+  From  string
+  To    string
+  Apply func(doc map[string]interface{}) []string
+}
+
+// registered migrations, one step per schema version
+var migrations = map[string]Migration{}
+
+func init() {
+  RegisterMigration(Migration{From: "0.2.0", To: "0.3.0", Apply: migrate020To030})
+  RegisterMigration(Migration{From: "0.3.0", To: "0.4.0", Apply: addedFields("0.4.0")})
+  RegisterMigration(Migration{From: "0.4.0", To: "0.5.0", Apply: addedFields("0.5.0")})
+  RegisterMigration(Migration{From: "0.5.0", To: "0.6.0", Apply: addedFields("0.6.0")})
+  RegisterMigration(Migration{From: "0.6.0", To: "0.7.0", Apply: addedFields("0.7.0")})
+  RegisterMigration(Migration{From: "0.7.0", To: "0.8.0", Apply: migrate070To080})
+  RegisterMigration(Migration{From: "0.8.0", To: "0.9.0", Apply: migrate080To090})
+  RegisterMigration(Migration{From: "0.9.0", To: "0.10.0", Apply: addedFields("0.10.0")})
+  RegisterMigration(Migration{From: "0.10.0", To: "0.11.0", Apply: migrate0100To0110})
+  RegisterMigration(Migration{From: "0.11.0", To: "0.12.0", Apply: migrate0110To0120})
+  RegisterMigration(Migration{From: "0.12.0", To: "0.13.0", Apply: addedFields("0.13.0")})
+  RegisterMigration(Migration{From: "0.13.0", To: "0.14.0", Apply: addedFields("0.14.0")})
+  RegisterMigration(Migration{From: "0.14.0", To: "0.15.0", Apply: addedFields("0.15.0")})
+}
+
+// RegisterMigration adds a migration step, replacing any from the same version
+func RegisterMigration(m Migration) {
+  migrations[m.From] = m
+}
+
+// MigrationResult records how a manifest was upgraded. Placeholders are new
+// fields left empty because only the METS has their values; NotCarried are
+// fields of the manifest the target schema has no place for.
+type MigrationResult struct {
+  From         string   `json:"from"`
+  To           string   `json:"to"`
+  Steps        []string `json:"steps"`
+  Placeholders []string `json:"placeholders"`
+  NotCarried   []string `json:"not_carried_over"`
+}
+
+// MigrateJSON upgrades a manifest to the target schema version, step by step
+// from the version it declares. Manifests reaching the current version are
+// re-encoded through ObjectMetsManifest so they match freshly built ones.
+func MigrateJSON(data []byte, target string) ([]byte, MigrationResult, error) {
+  result := MigrationResult{To: target, Steps: []string{}, Placeholders: []string{}, NotCarried: []string{}}
+  var doc map[string]interface{}
+  err := json.Unmarshal(data, &doc)
+  if err != nil {
+    return nil, result, err
+  }
+  version, _ := doc["schema_version"].(string)
+  result.From = version
+
+  for version != target {
+    m, ok := migrations[version]
+    if !ok {
+      return nil, result, fmt.Errorf("%w: no migration from %q towards %q", ErrUnknownSchemaVersion, version, target)
+    }
+    for _, field := range m.Apply(doc) {
+      result.Placeholders = addUnique(result.Placeholders, field)
+    }
+    doc["schema_version"] = m.To
+    result.Steps = append(result.Steps, m.From+" to "+m.To)
+    version = m.To
+  }
+
+  output, err := json.MarshalIndent(doc, "", "  ")
+  if err != nil {
+    return nil, result, err
+  }
+
+  // whatever the target schema doesn't allow is lost
+  schema, err := Schema(target)
+  if err == nil {
+    violations, err := ValidateJSON(schema, output)
+    if err != nil {
+      return nil, result, err
+    }
+    for _, v := range violations {
+      // "$.manifest: unexpected property \"name\"" -> "$.manifest.name"
+      i := strings.Index(v, ": unexpected property ")
+      if i < 0 {
+        continue
+      }
+      name, err := strconv.Unquote(v[i+len(": unexpected property "):])
+      if err != nil {
+        name = v[i+len(": unexpected property "):]
+      }
+      result.NotCarried = append(result.NotCarried, v[:i]+"."+name)
+    }
+  }
+
+  if target == SchemaVersion {
+    manifestObject := ObjectMetsManifest{}
+    err = json.Unmarshal(output, &manifestObject)
+    if err != nil {
+      return nil, result, err
+    }
+    output, err = json.MarshalIndent(&manifestObject, "", "  ")
+    if err != nil {
+      return nil, result, err
+    }
+  }
+  return output, result, nil
+}
+
+// 0.3.0 adds transfer metadata, rights, total size and warnings
+func migrate020To030(doc map[string]interface{}) []string {
+  var filled []string
+  placeholder := func(object map[string]interface{}, path string, name string, value interface{}) {
+    if setDefault(object, name, value) {
+      filled = addUnique(filled, path+"."+name)
+    }
+  }
+  // the METS is needed for bag-info, rights and bag totals
+  placeholder(doc, "$", "transfer_metadata", nil)
+  placeholder(doc, "$", "rights", nil)
+  placeholder(doc, "$", "access_restricted", false)
+  placeholder(doc, "$", "embargo_end_date", "")
+  placeholder(doc, "$", "warnings", nil)
+
+  var totalSize float64
+  manifest, _ := doc["manifest"].(map[string]interface{})
+  files, _ := manifest["files"].([]interface{})
+  for _, f := range files {
+    file, ok := f.(map[string]interface{})
+    if !ok {
+      continue
+    }
+    size, _ := file["filesize"].(float64)
+    totalSize += size
+    placeholder(file, "$.manifest.files[]", "rights", nil)
+    placeholder(file, "$.manifest.files[]", "access_restricted", false)
+    placeholder(file, "$.manifest.files[]", "embargo_end_date", "")
+  }
+  setDefault(doc, "total_size", totalSize)
+  return filled
+}
+
+// 0.8.0 lists agents once at package level, files and events reference them
+func migrate070To080(doc map[string]interface{}) []string {
+  var agents []interface{}
+  seen := make(map[string]bool)
+  manifest, _ := doc["manifest"].(map[string]interface{})
+  for _, section := range []string{SectionOriginals, SectionDerivatives, SectionSupporting} {
+    files, _ := manifest[section].([]interface{})
+    for _, f := range files {
+      file, _ := f.(map[string]interface{})
+      dc, ok := file["descriptiveMD"].(map[string]interface{})
+      if !ok {
+        continue
+      }
+      var ids []interface{}
+      embedded, _ := dc["agents"].([]interface{})
+      for _, a := range embedded {
+        agent, ok := a.(map[string]interface{})
+        if !ok {
+          continue
+        }
+        id := AgentId(stringField(agent, "identifier_type"), stringField(agent, "identifier_value"))
+        ids = append(ids, id)
+        if !seen[id] {
+          seen[id] = true
+          agent["id"] = id
+          agents = append(agents, agent)
+        }
+      }
+      delete(dc, "agents")
+      if len(ids) > 0 {
+        dc["agent_ids"] = ids
+      }
+      events, _ := dc["events"].([]interface{})
+      for _, e := range events {
+        event, _ := e.(map[string]interface{})
+        links, _ := event["linking_agents"].([]interface{})
+        for _, l := range links {
+          if link, ok := l.(map[string]interface{}); ok {
+            link["agent_id"] = AgentId(stringField(link, "identifier_type"), stringField(link, "identifier_value"))
+          }
+        }
+      }
+    }
+  }
+  doc["agents"] = agents
+  return nil
+}
+
+// 0.9.0 derives the event errors of each file and the package status from
+// the event outcomes
+func migrate080To090(doc map[string]interface{}) []string {
+  var status packageStatus
+  manifest, _ := doc["manifest"].(map[string]interface{})
+  for _, section := range []string{SectionOriginals, SectionDerivatives, SectionSupporting} {
+    files, _ := manifest[section].([]interface{})
+    for _, f := range files {
+      object, ok := f.(map[string]interface{})
+      if !ok {
+        continue
+      }
+      file := FilesMets{}
+      setEventErrors(&file, fileEvents(object))
+      object["event_errors"] = jsonValue(file.EventErrors)
+      if stringField(object, "errors") == "" {
+        object["errors"] = file.Errors
+      }
+      status.add(stringField(object, "filename"), file.EventErrors, nil)
+    }
+  }
+  doc["status"] = status.status()
+  if stringField(doc, "sf_errors") == "" {
+    doc["sf_errors"] = status.formatErrors()
+  }
+  return addedFields("0.9.0")(doc)
+}
+
+// the events of a file decoded from generic JSON
+func fileEvents(file map[string]interface{}) []Events {
+  dc, _ := file["descriptiveMD"].(map[string]interface{})
+  var events []Events
+  data, err := json.Marshal(dc["events"])
+  if err == nil {
+    json.Unmarshal(data, &events)
+  }
+  return events
+}
+
+// a Go value as generic JSON, so later steps can fill it like the rest
+func jsonValue(v interface{}) interface{} {
+  data, err := json.Marshal(v)
+  if err != nil {
+    return nil
+  }
+  var value interface{}
+  json.Unmarshal(data, &value)
+  return value
+}
+
+// 0.11.0 reads the tool of every event from its detail
+func migrate0100To0110(doc map[string]interface{}) []string {
+  eachEvent(doc, func(event map[string]interface{}) {
+    if _, ok := event["tool"]; ok {
+      return
+    }
+    event["tool"] = nil
+    tool, err := ParseEventDetail(stringField(event, "detail"))
+    if err == nil {
+      event["tool"] = tool
+    }
+  })
+  return nil
+}
+
+// 0.12.0 lists the tools that processed the package, from the event tools
+// (the FITS tools are only in the METS)
+func migrate0110To0120(doc map[string]interface{}) []string {
+  if _, ok := doc["tools"]; !ok {
+    var inventory toolInventory
+    var fileNumber int64
+    manifest, _ := doc["manifest"].(map[string]interface{})
+    for _, section := range []string{SectionOriginals, SectionDerivatives, SectionSupporting} {
+      files, _ := manifest[section].([]interface{})
+      for _, f := range files {
+        if file, ok := f.(map[string]interface{}); ok {
+          fileNumber++
+          inventory.add(fileNumber, fileEvents(file), Fits{})
+        }
+      }
+    }
+    doc["tools"] = jsonValue(inventory.list())
+  }
+  return addedFields("0.12.0")(doc)
+}
+
+// call fn with every event of every file, including those of related files
+func eachEvent(doc map[string]interface{}, fn func(event map[string]interface{})) {
+  manifest, _ := doc["manifest"].(map[string]interface{})
+  for _, section := range []string{SectionOriginals, SectionDerivatives, SectionSupporting} {
+    files, _ := manifest[section].([]interface{})
+    for _, f := range files {
+      file, _ := f.(map[string]interface{})
+      dc, _ := file["descriptiveMD"].(map[string]interface{})
+      events, _ := dc["events"].([]interface{})
+      for _, e := range events {
+        if event, ok := e.(map[string]interface{}); ok {
+          fn(event)
+        }
+      }
+      for _, relation := range []string{"derived_files", "source_files"} {
+        related, _ := file[relation].([]interface{})
+        for _, r := range related {
+          r, _ := r.(map[string]interface{})
+          if event, ok := r["event"].(map[string]interface{}); ok {
+            fn(event)
+          }
+        }
+      }
+    }
+  }
+}
+
+func stringField(object map[string]interface{}, name string) string {
+  value, _ := object[name].(string)
+  return value
+}
+
+// migration for versions that only add fields: every required property of
+// the target schema missing from the manifest gets its empty value, and is
+// reported as a placeholder
+func addedFields(version string) func(doc map[string]interface{}) []string {
+  return func(doc map[string]interface{}) []string {
+    data, err := Schema(version)
+    if err != nil {
+      return nil
+    }
+    var schema map[string]interface{}
+    err = json.Unmarshal(data, &schema)
+    if err != nil {
+      return nil
+    }
+    var filled []string
+    schemaDoc(schema).fillRequired(schema, doc, "$", &filled)
+    sort.Strings(filled)
+    return filled
+  }
+}
+
+// add missing required properties of a schema to a decoded JSON value,
+// recording their paths in filled when it isn't nil
+func (d schemaDoc) fillRequired(schema map[string]interface{}, value interface{}, path string, filled *[]string) {
+  schema = d.resolve(schema, value)
+  switch v := value.(type) {
+  case map[string]interface{}:
+    properties, _ := schema["properties"].(map[string]interface{})
+    required, _ := schema["required"].([]interface{})
+    for _, r := range required {
+      name, _ := r.(string)
+      if p, ok := properties[name].(map[string]interface{}); ok {
+        if setDefault(v, name, d.emptyValue(p)) && filled != nil {
+          *filled = addUnique(*filled, path+"."+name)
+        }
+      }
+    }
+    for name, p := range properties {
+      if p, ok := p.(map[string]interface{}); ok && v[name] != nil {
+        d.fillRequired(p, v[name], path+"."+name, filled)
+      }
+    }
+  case []interface{}:
+    if items, ok := schema["items"].(map[string]interface{}); ok {
+      for _, item := range v {
+        d.fillRequired(items, item, path+"[]", filled)
+      }
+    }
+  }
+}
+
+// the value encoding/json writes for a zero Go value of a schema type
+func (d schemaDoc) emptyValue(schema map[string]interface{}) interface{} {
+  if _, ok := schema["anyOf"]; ok {
+    // pointers to structs
+    return nil
+  }
+  schema = d.resolve(schema, nil)
+  t, ok := schema["type"].(string)
+  if !ok {
+    // nullable arrays, maps and pointers
+    return nil
+  }
+  switch t {
+  case "string":
+    return ""
+  case "boolean":
+    return false
+  case "integer", "number":
+    return float64(0)
+  case "object":
+    object := map[string]interface{}{}
+    d.fillRequired(schema, object, "", nil)
+    return object
+  }
+  return nil
+}
+
+// set a field only if the manifest doesn't already have it, true if it was set
+func setDefault(doc map[string]interface{}, name string, value interface{}) bool {
+  if _, ok := doc[name]; ok {
+    return false
+  }
+  doc[name] = value
+  return true
+}
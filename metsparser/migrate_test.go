@@ -0,0 +1,172 @@
+package metsparser
+
+import (
+  "encoding/json"
+  "io/ioutil"
+  "reflect"
+  "testing"
+)
+
+func readBaseline(t *testing.T) map[string]interface{} {
+  data, err := ioutil.ReadFile("testdata/manifest-0.2.0.json")
+  if err != nil {
+    t.Fatal(err)
+  }
+  var doc map[string]interface{}
+  err = json.Unmarshal(data, &doc)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return doc
+}
+
+// every published schema but the current one has a step to the next, and each
+// step turns a valid manifest into one valid against the next schema
+func TestMigrationSteps(t *testing.T) {
+  versions := SchemaVersions()
+  for i, version := range versions[:len(versions)-1] {
+    m, ok := migrations[version]
+    if !ok {
+      t.Fatalf("no migration from %s", version)
+    }
+    if m.To != versions[i+1] {
+      t.Errorf("migration from %s goes to %s, want %s", version, m.To, versions[i+1])
+    }
+  }
+
+  doc := readBaseline(t)
+  version := "0.2.0"
+  for version != SchemaVersion {
+    m := migrations[version]
+    t.Run(m.From+" to "+m.To, func(t *testing.T) {
+      m.Apply(doc)
+      doc["schema_version"] = m.To
+      data, err := json.Marshal(doc)
+      if err != nil {
+        t.Fatal(err)
+      }
+      schema, err := Schema(m.To)
+      if err != nil {
+        t.Fatal(err)
+      }
+      violations, err := ValidateJSON(schema, data)
+      if err != nil {
+        t.Fatal(err)
+      }
+      for _, v := range violations {
+        t.Error(v)
+      }
+    })
+    version = m.To
+  }
+}
+
+func TestMigrateJSONReportsPlaceholders(t *testing.T) {
+  data, err := ioutil.ReadFile("testdata/manifest-0.2.0.json")
+  if err != nil {
+    t.Fatal(err)
+  }
+  _, result, err := MigrateJSON(data, SchemaVersion)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(result.NotCarried) > 0 {
+    t.Errorf("not carried over %v, want none", result.NotCarried)
+  }
+  reported := make(map[string]bool)
+  for _, field := range result.Placeholders {
+    reported[field] = true
+  }
+  tests := []struct {
+    field       string
+    placeholder bool
+  }{
+    // only the METS has these
+    {"$.rights", true},
+    {"$.access_restricted", true},
+    {"$.manifest.files[].rights", true},
+    {"$.manifest.files[].use", true},
+    {"$.manifest.files[].uuid", true},
+    {"$.quality", true},
+    // derived from the manifest
+    {"$.total_size", false},
+    {"$.agents", false},
+    {"$.status", false},
+    {"$.manifest.files[].event_errors", false},
+    {"$.manifest.files[].descriptiveMD.events[].tool", false},
+    {"$.tools", false},
+  }
+  for _, tt := range tests {
+    if reported[tt.field] != tt.placeholder {
+      t.Errorf("%s: reported %v, want %v", tt.field, reported[tt.field], tt.placeholder)
+    }
+  }
+}
+
+func TestMigrateJSONReportsLostFields(t *testing.T) {
+  doc := readBaseline(t)
+  doc["legacy_note"] = "kept by an old tool"
+  data, err := json.Marshal(doc)
+  if err != nil {
+    t.Fatal(err)
+  }
+  _, result, err := MigrateJSON(data, SchemaVersion)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !reflect.DeepEqual(result.NotCarried, []string{"$.legacy_note"}) {
+    t.Errorf("not carried over %v, want $.legacy_note", result.NotCarried)
+  }
+}
+
+func TestMigrateJSONDerivesFields(t *testing.T) {
+  data, err := ioutil.ReadFile("testdata/manifest-0.2.0.json")
+  if err != nil {
+    t.Fatal(err)
+  }
+  output, _, err := MigrateJSON(data, SchemaVersion)
+  if err != nil {
+    t.Fatal(err)
+  }
+  m := ObjectMetsManifest{}
+  err = json.Unmarshal(output, &m)
+  if err != nil {
+    t.Fatal(err)
+  }
+  file := m.Manifest.Files[0]
+
+  // 0.3.0
+  if m.TotalSize != 5 {
+    t.Errorf("total_size %d, want 5", m.TotalSize)
+  }
+  // 0.8.0
+  if len(m.Agents) != 2 || m.Agents[0].ID != "preservation system:Archivematica-1.13" || m.Agents[1].ID != "preservation system:Archivematica-1.11" {
+    t.Errorf("agents %+v, want both Archivematica agents", m.Agents)
+  }
+  if len(file.DescriptiveMD.AgentIds) != 1 || file.DescriptiveMD.Agents != nil {
+    t.Errorf("file agents %+v, ids %v, want the agent id only", file.DescriptiveMD.Agents, file.DescriptiveMD.AgentIds)
+  }
+  // 0.9.0
+  if m.Status != StatusFailed {
+    t.Errorf("status %q, want %q", m.Status, StatusFailed)
+  }
+  if len(file.EventErrors) != 1 || file.EventErrors[0].Type != "virus check" {
+    t.Errorf("event_errors %+v, want the failed virus check", file.EventErrors)
+  }
+  if file.Errors != "virus check: Fail (Eicar-Signature FOUND)" {
+    t.Errorf("errors %q", file.Errors)
+  }
+  // 0.11.0
+  tool := file.DescriptiveMD.Events[0].Tool
+  if tool == nil || tool.Program != "ClamAV (clamd)" || tool.Params["virusdefinitions"] != "26311" {
+    t.Errorf("event tool %+v, want ClamAV", tool)
+  }
+  // 0.12.0
+  var programs []string
+  for _, tool := range m.Tools {
+    programs = append(programs, tool.Program)
+  }
+  if !reflect.DeepEqual(programs, []string{"ClamAV (clamd)", "Ghostscript", "Siegfried"}) {
+    t.Errorf("tools %v, want ClamAV, Ghostscript and Siegfried", programs)
+  }
+}
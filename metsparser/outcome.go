@@ -0,0 +1,150 @@
+package metsparser
+
+import (
+  "fmt"
+  "strings"
+)
+
+// severity of an event outcome
+const (
+  OutcomeFailed  = "failed"
+  OutcomeWarning = "warning"
+)
+
+// package status, from the worst event outcome of its files
+const (
+  StatusOk       = "ok"
+  StatusWarnings = "warnings"
+  StatusFailed   = "failed"
+)
+
+// DefaultCriticalEvents are the event types whose failure should stop a run
+var DefaultCriticalEvents = []string{"virus check", "fixity check"}
+
+// severity of a PREMIS eventOutcome, empty when it reports no problem.
+// Archivematica writes Pass/Fail, pass/fail, Positive/Negative and free text.
+func outcomeSeverity(outcome string) string {
+  o := strings.ToLower(strings.TrimSpace(outcome))
+  switch {
+  case o == "fail" || o == "failed" || o == "failure" || o == "error" || strings.HasPrefix(o, "fail"):
+    return OutcomeFailed
+  case o == "negative" || strings.Contains(o, "warning") || strings.Contains(o, "partial"):
+    return OutcomeWarning
+  }
+  return ""
+}
+
+// the events of a file that failed or passed with warnings
+func eventErrors(events []Events) []EventError {
+  var errs []EventError
+  for _, e := range events {
+    severity := outcomeSeverity(e.Outcome)
+    if severity == "" {
+      continue
+    }
+    errs = append(errs, EventError{Uuid: e.Uuid, Type: e.Type, Outcome: e.Outcome, Note: e.DetailNote, Severity: severity})
+  }
+  return errs
+}
+
+// set the event errors of a file, and their messages
+func setEventErrors(file *FilesMets, events []Events) {
+  file.EventErrors = eventErrors(events)
+  var messages []string
+  for _, e := range file.EventErrors {
+    messages = append(messages, e.Message())
+  }
+  file.Errors = strings.Join(messages, "; ")
+}
+
+// Message describes the problem in one line
+func (e EventError) Message() string {
+  message := e.Type + ": " + e.Outcome
+  if e.Note != "" {
+    message += " (" + e.Note + ")"
+  }
+  return message
+}
+
+// critical failures named in ErrCriticalEvent, the others are only counted
+const criticalListed = 10
+
+// worst outcome of the package, and the problems found by format identification
+type packageStatus struct {
+  failed         int
+  warnings       int
+  sfErrors       *list
+  critical       []string
+  criticalFailed int
+}
+
+// count the problems of a file, recording failures of critical event types
+func (p *packageStatus) add(file string, errs []EventError, critical []string) {
+  for _, e := range errs {
+    if e.Severity == OutcomeFailed {
+      p.failed++
+      if containsFold(critical, e.Type) {
+        p.criticalFailed++
+        if len(p.critical) < criticalListed {
+          p.critical = append(p.critical, file+": "+e.Message())
+        }
+      }
+    } else {
+      p.warnings++
+    }
+    if strings.EqualFold(e.Type, "format identification") {
+      if p.sfErrors == nil {
+        p.sfErrors = &list{}
+      }
+      p.sfErrors.add(file+": "+e.Message())
+    }
+  }
+}
+
+func (p *packageStatus) status() string {
+  switch {
+  case p.failed > 0:
+    return StatusFailed
+  case p.warnings > 0:
+    return StatusWarnings
+  }
+  return StatusOk
+}
+
+// the format identification problems, in one line
+func (p *packageStatus) formatErrors() string {
+  return strings.Join(p.sfErrors.strings(), "; ")
+}
+
+// set the package status and format identification problems, failing if a
+// critical event failed
+func (p *packageStatus) apply(m *ObjectMetsManifest) error {
+  m.SfErrors = p.formatErrors()
+  return p.applyStatus(m)
+}
+
+// set the package status, failing if a critical event failed
+func (p *packageStatus) applyStatus(m *ObjectMetsManifest) error {
+  m.Status = p.status()
+  if p.criticalFailed == 0 {
+    return nil
+  }
+  message := strings.Join(p.critical, "; ")
+  if p.criticalFailed > len(p.critical) {
+    message += fmt.Sprintf(" and %d more", p.criticalFailed-len(p.critical))
+  }
+  return fmt.Errorf("%w: %s", ErrCriticalEvent, message)
+}
+
+// UpdateStatus derives the event errors of each file and the package status
+// again from the events in the manifest, after events were added to it. Like
+// BuildManifest it returns ErrCriticalEvent if an event of a critical type
+// failed.
+func UpdateStatus(m *ObjectMetsManifest, criticalEvents []string) error {
+  var status packageStatus
+  for _, file := range m.Manifest.AllFiles() {
+    setEventErrors(file, file.DescriptiveMD.Events)
+    status.add(file.FileName, file.EventErrors, criticalEvents)
+  }
+  return status.apply(m)
+}
@@ -0,0 +1,144 @@
+package metsparser
+
+import (
+  "bytes"
+  "errors"
+  "fmt"
+  "io/ioutil"
+  "strings"
+  "testing"
+)
+
+func TestOutcomeSeverity(t *testing.T) {
+  tests := []struct {
+    outcome string
+    want    string
+  }{
+    {"Pass", ""},
+    {"pass", ""},
+    {"Positive", ""},
+    {"", ""},
+    {"Fail", OutcomeFailed},
+    {" fail ", OutcomeFailed},
+    {"Failed", OutcomeFailed},
+    {"failure", OutcomeFailed},
+    {"error", OutcomeFailed},
+    {"Fail (Eicar-Signature FOUND)", OutcomeFailed},
+    {"Negative", OutcomeWarning},
+    {"negative", OutcomeWarning},
+    // free text
+    {"Completed with warnings", OutcomeWarning},
+    {"partial success", OutcomeWarning},
+    {"No virus found", ""},
+    {"transcription succeeded", ""},
+  }
+  for _, tt := range tests {
+    got := outcomeSeverity(tt.outcome)
+    if got != tt.want {
+      t.Errorf("%q: severity %q, want %q", tt.outcome, got, tt.want)
+    }
+  }
+}
+
+func statusFile(name string, events ...Events) FilesMets {
+  file := FilesMets{FileName: name}
+  file.DescriptiveMD.Events = events
+  return file
+}
+
+func TestUpdateStatus(t *testing.T) {
+  tests := []struct {
+    name     string
+    files    []FilesMets
+    critical []string
+    status   string
+    sfErrors string
+    errors   string // of the first file
+    failed   bool   // with ErrCriticalEvent
+  }{
+    {"no events", []FilesMets{statusFile("a")}, nil, StatusOk, "", "", false},
+    {"passed", []FilesMets{statusFile("a", Events{Type: "virus check", Outcome: "Pass"})}, nil, StatusOk, "", "", false},
+    {"negative identification", []FilesMets{statusFile("a", Events{Type: "format identification", Outcome: "Negative"})}, nil,
+      StatusWarnings, "a: format identification: Negative", "format identification: Negative", false},
+    {"failure outweighs warning", []FilesMets{
+      statusFile("a", Events{Type: "validation", Outcome: "Completed with warnings"}),
+      statusFile("b", Events{Type: "validation", Outcome: "Fail", DetailNote: "not valid"}),
+    }, nil, StatusFailed, "", "validation: Completed with warnings", false},
+    {"critical failure", []FilesMets{statusFile("a", Events{Type: "Virus Check", Outcome: "Fail"})}, DefaultCriticalEvents,
+      StatusFailed, "", "Virus Check: Fail", true},
+    {"failure of a type not critical", []FilesMets{statusFile("a", Events{Type: "validation", Outcome: "Fail"})}, DefaultCriticalEvents,
+      StatusFailed, "", "validation: Fail", false},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      m := &ObjectMetsManifest{Status: "stale", SfErrors: "stale"}
+      m.Manifest.Files = tt.files
+      err := UpdateStatus(m, tt.critical)
+      if errors.Is(err, ErrCriticalEvent) != tt.failed {
+        t.Errorf("error %v, want critical %v", err, tt.failed)
+      }
+      if m.Status != tt.status {
+        t.Errorf("status %q, want %q", m.Status, tt.status)
+      }
+      if m.SfErrors != tt.sfErrors {
+        t.Errorf("sf_errors %q, want %q", m.SfErrors, tt.sfErrors)
+      }
+      if m.Manifest.Files[0].Errors != tt.errors {
+        t.Errorf("errors %q, want %q", m.Manifest.Files[0].Errors, tt.errors)
+      }
+    })
+  }
+}
+
+// the error names the first failures and counts the others
+func TestCriticalEventsListed(t *testing.T) {
+  m := &ObjectMetsManifest{}
+  for i := 0; i < criticalListed+2; i++ {
+    m.Manifest.Files = append(m.Manifest.Files, statusFile(fmt.Sprint("f", i), Events{Type: "fixity check", Outcome: "Fail"}))
+  }
+  err := UpdateStatus(m, DefaultCriticalEvents)
+  if !errors.Is(err, ErrCriticalEvent) {
+    t.Fatalf("error %v, want ErrCriticalEvent", err)
+  }
+  message := err.Error()
+  if !strings.Contains(message, "f9: fixity check: Fail") || strings.Contains(message, "f10:") || !strings.HasSuffix(message, " and 2 more") {
+    t.Errorf("error %q", message)
+  }
+}
+
+// -fail-critical sets Options.CriticalEvents; the fixture's validation failed
+func TestBuildManifestCriticalEvents(t *testing.T) {
+  data, err := ioutil.ReadFile("testdata/METS.abc.xml")
+  if err != nil {
+    t.Fatal(err)
+  }
+  tests := []struct {
+    critical []string
+    failed   bool
+  }{
+    {nil, false},
+    {DefaultCriticalEvents, false},
+    {[]string{"validation"}, true},
+  }
+  for _, tt := range tests {
+    mets, err := Parse(bytes.NewReader(data))
+    if err != nil {
+      t.Fatal(err)
+    }
+    m, err := BuildManifest(mets, Options{CriticalEvents: tt.critical})
+    if errors.Is(err, ErrCriticalEvent) != tt.failed || (err == nil) == tt.failed {
+      t.Errorf("%v: error %v, want critical %v", tt.critical, err, tt.failed)
+    }
+    if tt.failed && m != nil {
+      t.Errorf("%v: manifest built despite a critical failure", tt.critical)
+    }
+
+    streamed, err := NewDecoder(bytes.NewReader(data), int64(len(data))).Stream(Options{CriticalEvents: tt.critical}, nil)
+    if errors.Is(err, ErrCriticalEvent) != tt.failed {
+      t.Errorf("%v: streamed error %v, want critical %v", tt.critical, err, tt.failed)
+    }
+    if err == nil {
+      streamed.Close()
+    }
+  }
+}
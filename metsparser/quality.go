@@ -0,0 +1,166 @@
+package metsparser
+
+import (
+  "fmt"
+  "path"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+// quality warning codes
+const (
+  QualityExtensionMismatch = "extension_mismatch"
+  QualityZeroBytes         = "zero_bytes"
+  QualityUnidentified      = "unidentified"
+  QualitySizeMismatch      = "size_mismatch"
+)
+
+// expected extensions of common PRONOM formats, a format policy rule with
+// extensions takes precedence
+var formatExtensions = map[string][]string{
+  "fmt/3":     {"gif"},
+  "fmt/4":     {"gif"},
+  "fmt/11":    {"png"},
+  "fmt/12":    {"png"},
+  "fmt/13":    {"png"},
+  "fmt/14":    {"pdf"},
+  "fmt/15":    {"pdf"},
+  "fmt/16":    {"pdf"},
+  "fmt/17":    {"pdf"},
+  "fmt/18":    {"pdf"},
+  "fmt/19":    {"pdf"},
+  "fmt/20":    {"pdf"},
+  "fmt/40":    {"doc"},
+  "fmt/41":    {"jpg", "jpeg", "jpe"},
+  "fmt/42":    {"jpg", "jpeg", "jpe"},
+  "fmt/43":    {"jpg", "jpeg", "jpe"},
+  "fmt/44":    {"jpg", "jpeg", "jpe"},
+  "fmt/61":    {"xls"},
+  "fmt/95":    {"pdf"},
+  "fmt/96":    {"htm", "html"},
+  "fmt/99":    {"htm", "html"},
+  "fmt/100":   {"htm", "html"},
+  "fmt/101":   {"xml"},
+  "fmt/126":   {"ppt"},
+  "fmt/134":   {"mp3"},
+  "fmt/141":   {"wav"},
+  "fmt/142":   {"wav"},
+  "fmt/199":   {"mp4", "m4v", "m4a"},
+  "fmt/214":   {"xlsx"},
+  "fmt/215":   {"pptx"},
+  "fmt/276":   {"pdf"},
+  "fmt/353":   {"tif", "tiff"},
+  "fmt/354":   {"pdf"},
+  "fmt/412":   {"docx"},
+  "fmt/476":   {"pdf"},
+  "fmt/477":   {"pdf"},
+  "fmt/478":   {"pdf"},
+  "fmt/527":   {"wav"},
+  "fmt/569":   {"mkv", "mka"},
+  "fmt/645":   {"jpg", "jpeg"},
+  "fmt/1507":  {"jpg", "jpeg"},
+  "x-fmt/18":  {"csv"},
+  "x-fmt/111": {"txt", "text", "asc", "log", "md"},
+  "x-fmt/263": {"zip"},
+  "x-fmt/265": {"tar"},
+  "x-fmt/266": {"gz", "tgz"},
+  "x-fmt/384": {"mov", "qt"},
+  "x-fmt/387": {"tif", "tiff"},
+  "x-fmt/392": {"jp2"},
+  "x-fmt/398": {"jpg", "jpeg"},
+  "x-fmt/399": {"tif", "tiff"},
+  "x-fmt/430": {"msg"},
+}
+
+// the extensions expected for a PUID, nil when unknown
+func expectedExtensions(puid string, policy *FormatPolicy) []string {
+  puid = strings.TrimSpace(puid)
+  if policy != nil {
+    if rule, ok := policy.Formats[puid]; ok && len(rule.Extensions) > 0 {
+      return rule.Extensions
+    }
+  }
+  return formatExtensions[strings.ToLower(puid)]
+}
+
+// quality checks of a file: extension against its format, empty file,
+// no format identified and METS size against FITS size
+func qualityWarnings(file *FilesMets, name string, fits Fits, policy *FormatPolicy) []QualityWarning {
+  warnings := []QualityWarning{}
+  match := Matches{}
+  if len(file.Matches) > 0 {
+    match = file.Matches[0]
+  }
+
+  if unidentified(match) {
+    warnings = append(warnings, QualityWarning{Code: QualityUnidentified, Message: "no format identified"})
+  } else if expected := expectedExtensions(match.ID, policy); len(expected) > 0 {
+    ext := strings.TrimPrefix(path.Ext(name), ".")
+    if !containsFold(expected, ext) {
+      found := "no extension"
+      if ext != "" {
+        found = "extension ." + ext
+      }
+      warnings = append(warnings, QualityWarning{
+        Code:    QualityExtensionMismatch,
+        Message: fmt.Sprintf("%s doesn't match %s %s, expected .%s", found, match.ID, match.Format, strings.Join(expected, ", .")),
+      })
+    }
+  }
+
+  if file.FileSize == 0 {
+    warnings = append(warnings, QualityWarning{Code: QualityZeroBytes, Message: "file is empty"})
+  }
+
+  if fits.Size != "" {
+    size, err := strconv.ParseInt(strings.TrimSpace(fits.Size), 10, 64)
+    if err != nil || size != file.FileSize {
+      warnings = append(warnings, QualityWarning{
+        Code:    QualitySizeMismatch,
+        Message: fmt.Sprintf("METS size %d differs from FITS size %s", file.FileSize, strings.TrimSpace(fits.Size)),
+      })
+    }
+  }
+  return warnings
+}
+
+// quality warnings across the package
+type qualitySummary struct {
+  files    int64
+  warnings map[string]int64
+  flagged  *list
+}
+
+func (q *qualitySummary) add(file string, warnings []QualityWarning) {
+  if len(warnings) == 0 {
+    return
+  }
+  if q.warnings == nil {
+    q.warnings = make(map[string]int64)
+  }
+  if q.flagged == nil {
+    q.flagged = &list{}
+  }
+  q.files++
+  q.flagged.add(file)
+  for _, w := range warnings {
+    q.warnings[w.Code]++
+  }
+}
+
+// the summary without the files flagged
+func (q *qualitySummary) counts() QualitySummary {
+  summary := QualitySummary{FilesWithWarnings: q.files, Warnings: map[string]int64{}, Files: []string{}}
+  for code, count := range q.warnings {
+    summary.Warnings[code] = count
+  }
+  return summary
+}
+
+func (q *qualitySummary) result() QualitySummary {
+  summary := q.counts()
+  summary.Files = append(summary.Files, q.flagged.strings()...)
+  sort.Strings(summary.Files)
+  return summary
+}
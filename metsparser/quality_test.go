@@ -0,0 +1,81 @@
+package metsparser
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestQualityWarnings(t *testing.T) {
+  pdf := Matches{ID: "fmt/18", Format: "Acrobat PDF 1.4"}
+  policy := &FormatPolicy{Formats: map[string]FormatRule{
+    "fmt/18":    {Risk: "low", Extensions: []string{"ai"}},
+    "x-fmt/111": {Risk: "low"},
+  }}
+  tests := []struct {
+    name     string
+    file     string
+    match    Matches
+    size     int64
+    fitsSize string
+    policy   *FormatPolicy
+    codes    []string
+    message  string // of the first warning
+  }{
+    {"clean", "objects/a.pdf", pdf, 10, "10", nil, nil, ""},
+    {"extension case", "objects/A.PDF", pdf, 10, "", nil, nil, ""},
+    {"extension mismatch", "objects/a.txt", pdf, 10, "", nil, []string{QualityExtensionMismatch},
+      "extension .txt doesn't match fmt/18 Acrobat PDF 1.4, expected .pdf"},
+    {"no extension", "objects/README", Matches{ID: "x-fmt/111", Format: "Plain Text File"}, 10, "", nil, []string{QualityExtensionMismatch},
+      "no extension doesn't match x-fmt/111 Plain Text File, expected .txt, .text, .asc, .log, .md"},
+    {"format without known extensions", "objects/a.xyz", Matches{ID: "fmt/9999"}, 10, "", nil, nil, ""},
+    // a policy rule with extensions replaces the built in ones
+    {"policy extensions", "objects/a.ai", pdf, 10, "", policy, nil, ""},
+    {"policy extension mismatch", "objects/a.pdf", pdf, 10, "", policy, []string{QualityExtensionMismatch},
+      "extension .pdf doesn't match fmt/18 Acrobat PDF 1.4, expected .ai"},
+    {"policy rule without extensions", "objects/a.csv", Matches{ID: "x-fmt/111", Format: "Plain Text File"}, 10, "", policy, []string{QualityExtensionMismatch}, ""},
+    {"zero bytes", "objects/a.pdf", pdf, 0, "", nil, []string{QualityZeroBytes}, "file is empty"},
+    // the extension isn't checked against an unknown format
+    {"unidentified", "objects/a.bin", Matches{ID: "UNKNOWN"}, 10, "", nil, []string{QualityUnidentified}, "no format identified"},
+    {"no match", "objects/a.bin", Matches{}, 0, "", nil, []string{QualityUnidentified, QualityZeroBytes}, ""},
+    {"fits size mismatch", "objects/a.pdf", pdf, 10, " 12 ", nil, []string{QualitySizeMismatch}, "METS size 10 differs from FITS size 12"},
+    {"fits size unreadable", "objects/a.pdf", pdf, 10, "ten", nil, []string{QualitySizeMismatch}, "METS size 10 differs from FITS size ten"},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      file := &FilesMets{FileSize: tt.size}
+      if tt.match != (Matches{}) {
+        file.Matches = []Matches{tt.match}
+      }
+      warnings := qualityWarnings(file, tt.file, Fits{Size: tt.fitsSize}, tt.policy)
+      var codes []string
+      for _, w := range warnings {
+        codes = append(codes, w.Code)
+      }
+      if !reflect.DeepEqual(codes, tt.codes) {
+        t.Fatalf("warnings %v, want %v", codes, tt.codes)
+      }
+      if tt.message != "" && warnings[0].Message != tt.message {
+        t.Errorf("message %q, want %q", warnings[0].Message, tt.message)
+      }
+    })
+  }
+}
+
+func TestQualitySummary(t *testing.T) {
+  var q qualitySummary
+  q.add("objects/b.pdf", []QualityWarning{{Code: QualityZeroBytes}, {Code: QualitySizeMismatch}})
+  q.add("objects/clean.pdf", nil)
+  q.add("objects/a.pdf", []QualityWarning{{Code: QualityZeroBytes}})
+  want := QualitySummary{
+    FilesWithWarnings: 2,
+    Warnings:          map[string]int64{QualityZeroBytes: 2, QualitySizeMismatch: 1},
+    Files:             []string{"objects/a.pdf", "objects/b.pdf"},
+  }
+  if got := q.result(); !reflect.DeepEqual(got, want) {
+    t.Errorf("summary %+v, want %+v", got, want)
+  }
+  var empty qualitySummary
+  if got := empty.result(); got.Files == nil || got.Warnings == nil {
+    t.Errorf("empty summary %+v, want empty list and map", got)
+  }
+}
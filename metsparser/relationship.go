@@ -0,0 +1,101 @@
+package metsparser
+
+import (
+  "strings"
+)
+
+// PREMIS derivation subtypes, seen from the object holding the relationship
+const (
+  relationshipSourceOf  = "is source of"
+  relationshipHasSource = "has source"
+)
+
+// the parts of an amdSec that link its file to other files of the package
+type amdLinks struct {
+  ID            string               `xml:"ID,attr"`
+  Uuid          []string             `xml:"techMD>mdWrap>xmlData>object>objectIdentifier>objectIdentifierValue"`
+  Relationships []PremisRelationship `xml:"techMD>mdWrap>xmlData>object>relationship"`
+  DigiProvMD    []DigiProvMD         `xml:"digiprovMD"`
+}
+
+func linksOf(a AdminSec) amdLinks {
+  return amdLinks{
+    ID:            a.ID,
+    Uuid:          a.TechnicalMD.PremisObject.Uuid,
+    Relationships: a.TechnicalMD.PremisObject.Relationships,
+    DigiProvMD:    a.DigiProvMD,
+  }
+}
+
+// relations indexes every file by PREMIS object UUID, and the events
+// relationships point to, so a file can name its derivatives and sources
+// whichever amdSec they are described in
+type relations struct {
+  objects *table // amdSec ID keyed by object UUID
+  events  *table // Events keyed by event UUID
+  wanted  *table // event UUIDs named by a relationship
+}
+
+func newRelations(s *store) *relations {
+  return &relations{
+    objects: s.table(),
+    events:  s.table(),
+    wanted:  s.table(),
+  }
+}
+
+// record the object and events of an amdSec. Only normalization events and
+// events a relationship already named are kept, not the whole provenance.
+func (r *relations) add(l amdLinks) {
+  for _, uuid := range l.Uuid {
+    r.objects.put(uuid, l.ID)
+  }
+  for _, rel := range l.Relationships {
+    if rel.RelatedEventValue != "" {
+      r.wanted.put(rel.RelatedEventValue, true)
+    }
+  }
+  for _, digiprov := range l.DigiProvMD {
+    if digiprov.Premis.Mdtype != "PREMIS:EVENT" {
+      continue
+    }
+    event := premisEvent(digiprov.Premis.PremisEvent)
+    if r.wanted.has(event.Uuid) || strings.EqualFold(event.Type, "normalization") {
+      r.events.put(event.Uuid, event)
+    }
+  }
+}
+
+// resolve the derivation relationships of a file
+func (b *builder) relatedFiles(rels []PremisRelationship) ([]RelatedFile, []RelatedFile) {
+  var derived, sources []RelatedFile
+  for _, rel := range rels {
+    if !strings.EqualFold(rel.Type, "derivation") {
+      continue
+    }
+    related := RelatedFile{Uuid: rel.RelatedObjectValue}
+    if b.relations != nil {
+      var admid string
+      if b.relations.objects.get(rel.RelatedObjectValue, &admid) {
+        value, _ := b.fileMapped(admid)
+        related.FileName = value.Name
+        related.Use = value.Use
+      }
+      event := Events{}
+      if b.relations.events.get(rel.RelatedEventValue, &event) {
+        related.Event = &event
+      }
+    }
+    // an event described outside the amdSecs is still named
+    if related.Event == nil && rel.RelatedEventValue != "" {
+      related.Event = &Events{Uuid: rel.RelatedEventValue}
+    }
+    switch strings.ToLower(rel.SubType) {
+    case relationshipSourceOf:
+      derived = append(derived, related)
+    case relationshipHasSource:
+      sources = append(sources, related)
+    }
+  }
+  return derived, sources
+}
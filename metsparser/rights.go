@@ -0,0 +1,198 @@
+package metsparser
+
+import (
+  "encoding/json"
+  "strings"
+  "time"
+)
+
+// get PREMIS:RIGHTS statements for an object identified by AdminSec
+func getPremisRights(a AdminSec) []Rights {
+  var rights []Rights
+  for _, rightsmd := range a.RightsMD {
+    s := rightsmd.RightsStatement
+    r := Rights{}
+    r.Uuid = s.IdentifierValue
+    r.Basis = s.RightsBasis
+    switch strings.ToLower(s.RightsBasis) {
+    case "copyright":
+      c := s.CopyrightInformation
+      r.Status = c.Status
+      r.Jurisdiction = c.Jurisdiction
+      r.DeterminationDate = c.DeterminationDate
+      r.DocumentationIdentifier = strings.Join(c.DocumentationIdentifier, ",")
+      r.StartDate = c.StartDate
+      r.EndDate = c.EndDate
+      r.Note = strings.Join(c.Note, ",")
+    case "license":
+      l := s.LicenseInformation
+      r.Terms = l.Terms
+      r.DocumentationIdentifier = strings.Join(l.DocumentationIdentifier, ",")
+      r.StartDate = l.StartDate
+      r.EndDate = l.EndDate
+      r.Note = strings.Join(l.Note, ",")
+    case "statute":
+      var jurisdictions, citations, dates, documentation, notes []string
+      for _, st := range s.StatuteInformation {
+        jurisdictions = append(jurisdictions, st.Jurisdiction)
+        citations = append(citations, st.Citation)
+        dates = append(dates, st.DeterminationDate)
+        documentation = append(documentation, st.DocumentationIdentifier...)
+        notes = append(notes, st.Note...)
+        r.StartDate = st.StartDate
+        r.EndDate = st.EndDate
+      }
+      r.Jurisdiction = strings.Join(jurisdictions, ",")
+      r.Citation = strings.Join(citations, ",")
+      r.DeterminationDate = strings.Join(dates, ",")
+      r.DocumentationIdentifier = strings.Join(documentation, ",")
+      r.Note = strings.Join(notes, ",")
+    default:
+      o := s.OtherRightsInformation
+      if o.Basis != "" {
+        r.Basis = o.Basis
+      }
+      r.DocumentationIdentifier = strings.Join(o.DocumentationIdentifier, ",")
+      r.StartDate = o.StartDate
+      r.EndDate = o.EndDate
+      r.Note = strings.Join(o.Note, ",")
+    }
+    for _, g := range s.RightsGranted {
+      granted := RightsGranted{}
+      granted.Act = g.Act
+      granted.Restriction = strings.Join(g.Restriction, ",")
+      granted.StartDate = g.GrantStartDate
+      granted.EndDate = g.GrantEndDate
+      granted.RestrictionStartDate = g.RestrictionStartDate
+      granted.RestrictionEndDate = g.RestrictionEndDate
+      granted.Note = strings.Join(g.Note, ",")
+      r.Granted = append(r.Granted, granted)
+    }
+    rights = append(rights, r)
+  }
+  return rights
+}
+
+// return whether the rights restrict access on date, and the latest date an
+// active restriction ends; the end date is empty when a restriction is open ended
+func accessRestriction(rights []Rights, date time.Time) (bool, string) {
+  var a accessSummary
+  for _, r := range rights {
+    a.add(r, date)
+  }
+  return a.result()
+}
+
+// access restriction of rights statements as they are read
+type accessSummary struct {
+  restricted     bool
+  openEnded      bool
+  embargo        time.Time
+  embargoEndDate string
+}
+
+func (a *accessSummary) add(r Rights, date time.Time) {
+  for _, g := range r.Granted {
+    if !isAccessAct(g.Act) || !isRestrictive(g.Restriction) {
+      continue
+    }
+    start, end := g.StartDate, g.EndDate
+    if g.RestrictionStartDate != "" || g.RestrictionEndDate != "" {
+      start, end = g.RestrictionStartDate, g.RestrictionEndDate
+    }
+    startDate, ok := parseRightsDate(start)
+    if ok && date.Before(startDate) {
+      continue
+    }
+    endDate, ok := parseRightsDate(end)
+    if !ok {
+      a.restricted = true
+      a.openEnded = true
+      continue
+    }
+    if !date.Before(endDate) {
+      continue
+    }
+    a.restricted = true
+    if endDate.After(a.embargo) {
+      a.embargo = endDate
+      a.embargoEndDate = end
+    }
+  }
+}
+
+func (a *accessSummary) result() (bool, string) {
+  if a.openEnded {
+    return a.restricted, ""
+  }
+  return a.restricted, a.embargoEndDate
+}
+
+// acts that control whether users can get at the content
+func isAccessAct(act string) bool {
+  switch strings.ToLower(strings.TrimSpace(act)) {
+  case "disseminate", "access", "publish":
+    return true
+  }
+  return false
+}
+
+// Archivematica restrictions are Allow, Disallow or Conditional
+func isRestrictive(restriction string) bool {
+  restriction = strings.ToLower(restriction)
+  return strings.Contains(restriction, "disallow") || strings.Contains(restriction, "conditional")
+}
+
+// parse a PREMIS date, false for empty, "OPEN" or unknown values
+func parseRightsDate(value string) (time.Time, bool) {
+  value = strings.TrimSpace(value)
+  if len(value) < 10 {
+    return time.Time{}, false
+  }
+  t, err := time.Parse("2006-01-02", value[:10])
+  if err != nil {
+    return time.Time{}, false
+  }
+  return t, true
+}
+
+// rights statements of the whole package, each statement once, and the
+// access restriction they amount to
+type packageRights struct {
+  seen   *table
+  rights *list
+  access accessSummary
+}
+
+func (p *packageRights) add(rights []Rights, date time.Time) {
+  if p.seen == nil {
+    p.seen, p.rights = &table{}, &list{}
+  }
+  for _, r := range rights {
+    key := r.Uuid
+    if key == "" {
+      key = r.Basis + "|" + r.Status + "|" + r.Terms + "|" + r.Citation + "|" + r.Note
+    }
+    if p.seen.has(key) {
+      continue
+    }
+    p.seen.put(key, true)
+    p.rights.add(r)
+    p.access.add(r, date)
+  }
+}
+
+// the rights statements, nil if there are none
+func (p *packageRights) statements() []Rights {
+  var rights []Rights
+  r := p.rights.reader()
+  for {
+    data, ok := r.next()
+    if !ok {
+      return rights
+    }
+    statement := Rights{}
+    json.Unmarshal(data, &statement)
+    rights = append(rights, statement)
+  }
+}
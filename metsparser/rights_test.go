@@ -0,0 +1,47 @@
+package metsparser
+
+import (
+  "testing"
+  "time"
+)
+
+func TestAccessRestriction(t *testing.T) {
+  date := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+  grant := func(act, restriction, start, end string) Rights {
+    return Rights{Granted: []RightsGranted{{Act: act, Restriction: restriction, StartDate: start, EndDate: end}}}
+  }
+  tests := []struct {
+    name       string
+    rights     []Rights
+    restricted bool
+    endDate    string
+  }{
+    {"no rights", nil, false, ""},
+    {"embargo", []Rights{grant("Disseminate", "Disallow", "2020-01-01", "2030-01-01")}, true, "2030-01-01"},
+    {"conditional", []Rights{grant("access", "Conditional", "", "2030-01-01")}, true, "2030-01-01"},
+    {"expired", []Rights{grant("Disseminate", "Disallow", "2020-01-01", "2026-06-01")}, false, ""},
+    {"not started", []Rights{grant("Disseminate", "Disallow", "2027-01-01", "2030-01-01")}, false, ""},
+    {"open ended", []Rights{grant("Publish", "Disallow", "2020-01-01", "OPEN")}, true, ""},
+    {"allowed", []Rights{grant("Disseminate", "Allow", "", "")}, false, ""},
+    {"not an access act", []Rights{grant("Replicate", "Disallow", "", "")}, false, ""},
+    {"restriction dates win", []Rights{{Granted: []RightsGranted{{Act: "Disseminate", Restriction: "Disallow",
+      StartDate: "2020-01-01", EndDate: "2021-01-01", RestrictionStartDate: "2020-01-01", RestrictionEndDate: "2028-01-01"}}}}, true, "2028-01-01"},
+    {"latest end", []Rights{
+      grant("Disseminate", "Disallow", "", "2028-01-01"),
+      grant("Disseminate", "Disallow", "", "2030-01-01"),
+      grant("Disseminate", "Disallow", "", "2029-01-01"),
+    }, true, "2030-01-01"},
+    {"open ended beats a date", []Rights{
+      grant("Disseminate", "Disallow", "", "2030-01-01"),
+      grant("Disseminate", "Disallow", "", ""),
+    }, true, ""},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      restricted, endDate := accessRestriction(tt.rights, date)
+      if restricted != tt.restricted || endDate != tt.endDate {
+        t.Errorf("got %v %q, want %v %q", restricted, endDate, tt.restricted, tt.endDate)
+      }
+    })
+  }
+}
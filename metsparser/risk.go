@@ -0,0 +1,117 @@
+package metsparser
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "sort"
+  "strings"
+
+  "gopkg.in/yaml.v3"
+)
+
+// FormatPolicy is a local registry of preservation risk per PRONOM PUID
+type FormatPolicy struct {
+  // rules keyed by PUID
+  Formats map[string]FormatRule `json:"formats" yaml:"formats"`
+  // rule for identified formats the registry doesn't list
+  Default FormatRule `json:"default" yaml:"default"`
+  // rule for files no format was identified for
+  Unidentified FormatRule `json:"unidentified" yaml:"unidentified"`
+  // risk levels a package is flagged for, "high" when empty
+  HighRisk []string `json:"high_risk" yaml:"high_risk"`
+}
+
+// ParseFormatPolicy reads a format policy written in JSON, or in YAML with the
+// same structure
+func ParseFormatPolicy(data []byte) (*FormatPolicy, error) {
+  policy := FormatPolicy{}
+  var err error
+  if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+    err = json.Unmarshal(data, &policy)
+  } else {
+    err = yaml.Unmarshal(data, &policy)
+  }
+  if err != nil {
+    return nil, fmt.Errorf("%w: %v", ErrMalformedPolicy, err)
+  }
+  return &policy, nil
+}
+
+// FormatRule is the policy for one format
+type FormatRule struct {
+  Risk      string   `json:"risk" yaml:"risk"`
+  Preferred []string `json:"preferred" yaml:"preferred"`
+  Note      string   `json:"note" yaml:"note"`
+  // extensions files of the format are expected to have, see quality.go
+  Extensions []string `json:"extensions" yaml:"extensions"`
+}
+
+// risk levels used when the policy leaves them out
+const (
+  RiskUnassessed = "unassessed"
+  RiskHigh       = "high"
+)
+
+// the rule of a file's primary match
+func (p *FormatPolicy) assess(match Matches) FormatRisk {
+  rule, ok := p.Formats[strings.TrimSpace(match.ID)]
+  switch {
+  case unidentified(match):
+    rule = p.Unidentified
+    if rule.Risk == "" {
+      rule.Risk = RiskHigh
+    }
+  case !ok:
+    rule = p.Default
+    if rule.Risk == "" {
+      rule.Risk = RiskUnassessed
+    }
+  }
+  if rule.Preferred == nil {
+    rule.Preferred = []string{}
+  }
+  return FormatRisk{Puid: match.ID, Risk: rule.Risk, Preferred: rule.Preferred, Note: rule.Note, Unidentified: unidentified(match)}
+}
+
+func (p *FormatPolicy) isHighRisk(risk string) bool {
+  if len(p.HighRisk) == 0 {
+    return strings.EqualFold(risk, RiskHigh)
+  }
+  return containsFold(p.HighRisk, risk)
+}
+
+// files per risk level across the package
+type riskSummary struct {
+  policy  *FormatPolicy
+  summary RiskSummary
+}
+
+func (r *riskSummary) add(risk FormatRisk) {
+  if r.summary.Levels == nil {
+    r.summary.Levels = make(map[string]int64)
+    r.summary.HighRiskPuids = []string{}
+  }
+  r.summary.Levels[risk.Risk]++
+  if risk.Unidentified {
+    r.summary.UnidentifiedFiles++
+  }
+  if r.policy.isHighRisk(risk.Risk) {
+    r.summary.HighRiskFiles++
+    if risk.Puid != "" {
+      r.summary.HighRiskPuids = addUnique(r.summary.HighRiskPuids, risk.Puid)
+    }
+  }
+}
+
+func (r *riskSummary) result() *RiskSummary {
+  s := r.summary
+  if s.Levels == nil {
+    s.Levels = map[string]int64{}
+    s.HighRiskPuids = []string{}
+  }
+  s.HighRisk = s.HighRiskFiles > 0
+  s.Unidentified = s.UnidentifiedFiles > 0
+  sort.Strings(s.HighRiskPuids)
+  return &s
+}
@@ -0,0 +1,141 @@
+package metsparser
+
+import (
+  "errors"
+  "reflect"
+  "testing"
+)
+
+func TestParseFormatPolicy(t *testing.T) {
+  want := &FormatPolicy{
+    Formats: map[string]FormatRule{
+      "fmt/353":   {Risk: "low", Note: "TIFF"},
+      "x-fmt/111": {Risk: "high", Preferred: []string{"fmt/95"}, Note: "plain text: see 'notes'", Extensions: []string{"txt", "csv"}},
+    },
+    Default:  FormatRule{Risk: "medium"},
+    HighRisk: []string{"high", "critical"},
+  }
+  tests := []struct {
+    name   string
+    policy string
+  }{
+    {"json", `{
+      "formats": {
+        "fmt/353": {"risk": "low", "note": "TIFF"},
+        "x-fmt/111": {"risk": "high", "preferred": ["fmt/95"], "note": "plain text: see 'notes'", "extensions": ["txt", "csv"]}
+      },
+      "default": {"risk": "medium"},
+      "high_risk": ["high", "critical"]
+    }`},
+    {"yaml block", `
+# local registry
+formats:
+  fmt/353:
+    risk: low
+    note: TIFF # baseline
+  "x-fmt/111":
+    risk: high
+    preferred:
+      - fmt/95
+    note: "plain text: see 'notes'"
+    extensions:
+    - txt
+    - csv
+default:
+  risk: medium
+high_risk:
+  - high
+  - 'critical'
+`},
+    {"yaml flow", `---
+formats:
+  fmt/353: {risk: low, note: TIFF}
+  x-fmt/111: {risk: high, preferred: [fmt/95], note: 'plain text: see ''notes''', extensions: [txt, "csv"]}
+default: {risk: medium}
+high_risk: [high, critical]
+`},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      got, err := ParseFormatPolicy([]byte(tt.policy))
+      if err != nil {
+        t.Fatal(err)
+      }
+      if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %+v, want %+v", got, want)
+      }
+    })
+  }
+}
+
+// anchors and multi-line scalars are read as YAML defines them
+func TestParseFormatPolicyYAML(t *testing.T) {
+  policy := `
+formats:
+  fmt/353: &low
+    risk: low
+  fmt/354: *low
+  x-fmt/111:
+    risk: high
+    note: >
+      Plain text,
+      migrate to PDF/A
+`
+  got, err := ParseFormatPolicy([]byte(policy))
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := map[string]FormatRule{
+    "fmt/353":   {Risk: "low"},
+    "fmt/354":   {Risk: "low"},
+    "x-fmt/111": {Risk: "high", Note: "Plain text, migrate to PDF/A\n"},
+  }
+  if !reflect.DeepEqual(got.Formats, want) {
+    t.Errorf("got %+v, want %+v", got.Formats, want)
+  }
+}
+
+func TestParseFormatPolicyMalformed(t *testing.T) {
+  tests := []struct {
+    name   string
+    policy string
+  }{
+    {"json", `{"formats": [}`},
+    {"unterminated flow", "formats:\n  fmt/353: {risk: low\n"},
+    {"unterminated string", "default:\n  risk: \"low\n"},
+    {"bad indentation", "default:\n    risk: low\n  note: x\n"},
+    {"list in mapping", "default:\n  risk: low\n  - x\n"},
+    {"not key value", "default\n"},
+    {"wrong type", "high_risk: high\n"},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      _, err := ParseFormatPolicy([]byte(tt.policy))
+      if !errors.Is(err, ErrMalformedPolicy) {
+        t.Errorf("got %v, want ErrMalformedPolicy", err)
+      }
+    })
+  }
+}
+
+func TestFormatPolicyAssess(t *testing.T) {
+  policy := &FormatPolicy{
+    Formats:  map[string]FormatRule{"fmt/353": {Risk: "low"}},
+    HighRisk: []string{"high"},
+  }
+  tests := []struct {
+    match Matches
+    risk  string
+  }{
+    {Matches{ID: "fmt/353"}, "low"},
+    {Matches{ID: "fmt/999"}, RiskUnassessed},
+    {Matches{ID: ""}, RiskHigh},
+    {Matches{ID: "UNKNOWN"}, RiskHigh},
+  }
+  for _, tt := range tests {
+    got := policy.assess(tt.match)
+    if got.Risk != tt.risk {
+      t.Errorf("%q: got risk %q, want %q", tt.match.ID, got.Risk, tt.risk)
+    }
+  }
+}
@@ -0,0 +1,299 @@
+package metsparser
+
+import (
+  "embed"
+  "encoding/json"
+  "fmt"
+  "reflect"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+//go:generate go run .. schema -o schema
+
+// SchemaVersion of the manifests BuildManifest produces
+const SchemaVersion = "0.15.0"
+
+// published JSON Schemas, one per manifest version
+//go:embed schema/*.json
+var schemaFiles embed.FS
+
+// SchemaVersions lists the manifest versions with a published schema
+func SchemaVersions() []string {
+  entries, _ := schemaFiles.ReadDir("schema")
+  var versions []string
+  for _, e := range entries {
+    name := e.Name()
+    versions = append(versions, strings.TrimSuffix(strings.TrimPrefix(name, "manifest-"), ".json"))
+  }
+  sort.Slice(versions, func(i, j int) bool { return compareVersions(versions[i], versions[j]) < 0 })
+  return versions
+}
+
+// compare dotted version numbers field by field
+func compareVersions(a string, b string) int {
+  as, bs := strings.Split(a, "."), strings.Split(b, ".")
+  for i := 0; i < len(as) || i < len(bs); i++ {
+    var x, y int
+    if i < len(as) {
+      x, _ = strconv.Atoi(as[i])
+    }
+    if i < len(bs) {
+      y, _ = strconv.Atoi(bs[i])
+    }
+    if x != y {
+      if x < y {
+        return -1
+      }
+      return 1
+    }
+  }
+  return 0
+}
+
+// Schema returns the published JSON Schema of a manifest version
+func Schema(version string) ([]byte, error) {
+  data, err := schemaFiles.ReadFile("schema/manifest-" + version + ".json")
+  if err != nil {
+    return nil, fmt.Errorf("%w: %q, published versions are %s", ErrUnknownSchemaVersion, version, strings.Join(SchemaVersions(), ", "))
+  }
+  return data, nil
+}
+
+// GenerateSchema builds the JSON Schema of the current manifest structs. Each
+// struct type is described once under definitions and referenced with $ref.
+func GenerateSchema() map[string]interface{} {
+  g := schemaGenerator{definitions: make(map[string]interface{})}
+  schema := g.structSchema(reflect.TypeOf(ObjectMetsManifest{}))
+  schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+  schema["$id"] = "canopus-manifest-" + SchemaVersion
+  schema["title"] = "Canopus METS manifest " + SchemaVersion
+  properties := schema["properties"].(map[string]interface{})
+  properties["schema_version"] = map[string]interface{}{"type": "string", "const": SchemaVersion}
+  schema["definitions"] = g.definitions
+  return schema
+}
+
+// collects the definitions of the struct types a schema references
+type schemaGenerator struct {
+  definitions map[string]interface{}
+}
+
+// JSON Schema of a Go type, following encoding/json field naming
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]interface{} {
+  switch t.Kind() {
+  case reflect.String:
+    return map[string]interface{}{"type": "string"}
+  case reflect.Bool:
+    return map[string]interface{}{"type": "boolean"}
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+    reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+    return map[string]interface{}{"type": "integer"}
+  case reflect.Float32, reflect.Float64:
+    return map[string]interface{}{"type": "number"}
+  case reflect.Slice, reflect.Array:
+    // nil slices are written as null
+    return map[string]interface{}{"type": []interface{}{"array", "null"}, "items": g.schemaFor(t.Elem())}
+  case reflect.Map:
+    return map[string]interface{}{"type": []interface{}{"object", "null"}, "additionalProperties": g.schemaFor(t.Elem())}
+  case reflect.Ptr:
+    s := g.schemaFor(t.Elem())
+    if _, ok := s["$ref"]; ok {
+      return map[string]interface{}{"anyOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+    }
+    if _, ok := s["type"].(string); ok {
+      s["type"] = []interface{}{s["type"], "null"}
+    }
+    return s
+  case reflect.Struct:
+    if t.Name() == "" {
+      return g.structSchema(t)
+    }
+    if _, ok := g.definitions[t.Name()]; !ok {
+      // claimed before the fields are described, in case they refer back
+      g.definitions[t.Name()] = nil
+      g.definitions[t.Name()] = g.structSchema(t)
+    }
+    return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+  }
+  return map[string]interface{}{}
+}
+
+// the object schema of a struct's exported fields
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+  properties := make(map[string]interface{})
+  required := []interface{}{}
+  for i := 0; i < t.NumField(); i++ {
+    f := t.Field(i)
+    name, omitempty := jsonFieldName(f)
+    if name == "" {
+      continue
+    }
+    properties[name] = g.schemaFor(f.Type)
+    if !omitempty {
+      required = append(required, name)
+    }
+  }
+  return map[string]interface{}{
+    "type":                 "object",
+    "properties":           properties,
+    "required":             required,
+    "additionalProperties": false,
+  }
+}
+
+// name and omitempty flag of a struct field as encoding/json sees it, no name
+// when the field isn't encoded
+func jsonFieldName(f reflect.StructField) (string, bool) {
+  tag := f.Tag.Get("json")
+  if f.PkgPath != "" || tag == "-" {
+    return "", false
+  }
+  parts := strings.Split(tag, ",")
+  name := parts[0]
+  if name == "" {
+    name = f.Name
+  }
+  omitempty := false
+  for _, option := range parts[1:] {
+    if option == "omitempty" {
+      omitempty = true
+    }
+  }
+  return name, omitempty
+}
+
+// ValidateJSON checks a JSON document against a schema and returns every
+// violation found. Only the keywords GenerateSchema emits are supported, with
+// $refs into the schema's definitions.
+func ValidateJSON(schema []byte, document []byte) ([]string, error) {
+  var s map[string]interface{}
+  err := json.Unmarshal(schema, &s)
+  if err != nil {
+    return nil, fmt.Errorf("schema: %w", err)
+  }
+  var doc interface{}
+  err = json.Unmarshal(document, &doc)
+  if err != nil {
+    return nil, err
+  }
+  violations := []string{}
+  schemaDoc(s).validate(s, doc, "$", &violations)
+  return violations, nil
+}
+
+// a decoded JSON Schema, holding the definitions its $refs point to
+type schemaDoc map[string]interface{}
+
+// the schema a $ref points to; of anyOf, the alternative whose type value
+// has, or the first one; any other schema as it is
+func (d schemaDoc) resolve(schema map[string]interface{}, value interface{}) map[string]interface{} {
+  if ref, ok := schema["$ref"].(string); ok {
+    definitions, _ := d["definitions"].(map[string]interface{})
+    resolved, _ := definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{})
+    return resolved
+  }
+  if alternatives, ok := schema["anyOf"].([]interface{}); ok && len(alternatives) > 0 {
+    var first map[string]interface{}
+    for _, a := range alternatives {
+      a, _ := a.(map[string]interface{})
+      a = d.resolve(a, value)
+      if first == nil {
+        first = a
+      }
+      if matchesType(a["type"], value) {
+        return a
+      }
+    }
+    return first
+  }
+  return schema
+}
+
+func (d schemaDoc) validate(schema map[string]interface{}, value interface{}, path string, violations *[]string) {
+  schema = d.resolve(schema, value)
+  if t, ok := schema["type"]; ok && !matchesType(t, value) {
+    *violations = append(*violations, fmt.Sprintf("%s: expected %v, got %s", path, t, jsonType(value)))
+    return
+  }
+  if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, value) {
+    *violations = append(*violations, fmt.Sprintf("%s: expected %v, got %v", path, c, value))
+  }
+
+  switch v := value.(type) {
+  case map[string]interface{}:
+    properties, _ := schema["properties"].(map[string]interface{})
+    if required, ok := schema["required"].([]interface{}); ok {
+      for _, r := range required {
+        name, _ := r.(string)
+        if _, ok := v[name]; !ok {
+          *violations = append(*violations, fmt.Sprintf("%s: missing required property %q", path, name))
+        }
+      }
+    }
+    var names []string
+    for name := range v {
+      names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+      if p, ok := properties[name].(map[string]interface{}); ok {
+        d.validate(p, v[name], path+"."+name, violations)
+        continue
+      }
+      switch additional := schema["additionalProperties"].(type) {
+      case bool:
+        if !additional {
+          *violations = append(*violations, fmt.Sprintf("%s: unexpected property %q", path, name))
+        }
+      case map[string]interface{}:
+        d.validate(additional, v[name], path+"."+name, violations)
+      }
+    }
+  case []interface{}:
+    if items, ok := schema["items"].(map[string]interface{}); ok {
+      for i, item := range v {
+        d.validate(items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+      }
+    }
+  }
+}
+
+// true if value has the schema type, or one of them
+func matchesType(t interface{}, value interface{}) bool {
+  switch t := t.(type) {
+  case string:
+    actual := jsonType(value)
+    return actual == t || (t == "number" && actual == "integer")
+  case []interface{}:
+    for _, one := range t {
+      if matchesType(one, value) {
+        return true
+      }
+    }
+  }
+  return false
+}
+
+// JSON Schema type name of a decoded JSON value
+func jsonType(value interface{}) string {
+  switch v := value.(type) {
+  case nil:
+    return "null"
+  case bool:
+    return "boolean"
+  case float64:
+    if v == float64(int64(v)) {
+      return "integer"
+    }
+    return "number"
+  case string:
+    return "string"
+  case []interface{}:
+    return "array"
+  case map[string]interface{}:
+    return "object"
+  }
+  return "unknown"
+}
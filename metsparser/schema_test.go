@@ -0,0 +1,75 @@
+package metsparser
+
+import (
+  "bytes"
+  "encoding/json"
+  "testing"
+)
+
+// the published schema of the current version must be what the structs give,
+// regenerate it with go generate when they change
+func TestGenerateSchemaMatchesPublished(t *testing.T) {
+  generated, err := json.MarshalIndent(GenerateSchema(), "", "  ")
+  if err != nil {
+    t.Fatal(err)
+  }
+  generated = append(generated, '\n')
+  published, err := Schema(SchemaVersion)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !bytes.Equal(generated, published) {
+    t.Errorf("schema/manifest-%s.json is out of date, run go generate", SchemaVersion)
+  }
+}
+
+func TestValidateJSON(t *testing.T) {
+  schema, err := json.Marshal(GenerateSchema())
+  if err != nil {
+    t.Fatal(err)
+  }
+  m, err := BuildManifest(parseFixture(t, "METS.abc.xml"), Options{EmbedAgents: true})
+  if err != nil {
+    t.Fatal(err)
+  }
+  valid, err := json.Marshal(m)
+  if err != nil {
+    t.Fatal(err)
+  }
+  violations, err := ValidateJSON(schema, valid)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(violations) > 0 {
+    t.Errorf("built manifest: %q", violations)
+  }
+
+  var doc map[string]interface{}
+  json.Unmarshal(valid, &doc)
+  manifest := doc["manifest"].(map[string]interface{})
+  file := manifest["files"].([]interface{})[0].(map[string]interface{})
+  file["filesize"] = "5"
+  file["extra"] = true
+  delete(file, "use")
+  // a struct behind a pointer may be null, but nothing other than its definition
+  file["format_risk"] = "high"
+  invalid, _ := json.Marshal(doc)
+  violations, err = ValidateJSON(schema, invalid)
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := []string{
+    `$.manifest.files[0]: missing required property "use"`,
+    `$.manifest.files[0]: unexpected property "extra"`,
+    `$.manifest.files[0].filesize: expected integer, got string`,
+    `$.manifest.files[0].format_risk: expected object, got string`,
+  }
+  if len(violations) != len(want) {
+    t.Fatalf("got %q, want %q", violations, want)
+  }
+  for i := range want {
+    if violations[i] != want[i] {
+      t.Errorf("got %q, want %q", violations[i], want[i])
+    }
+  }
+}
@@ -0,0 +1,363 @@
+package metsparser
+
+import (
+  "bufio"
+  "encoding/binary"
+  "encoding/json"
+  "hash/fnv"
+  "io"
+  "io/ioutil"
+  "os"
+  "sort"
+)
+
+// buckets of a disk table, their heads are all it keeps in memory
+const tableBuckets = 1 << 16
+
+// strings sorted in memory at once when sorting a disk list
+const sortRun = 4096
+
+// store makes the tables and lists a build keeps per file. A store backs them
+// with temporary files, so memory stays the same however many files a package
+// has, and keeps the first error reading or writing them. A nil store, like
+// the zero table and list, keeps them in memory.
+type store struct {
+  temps []*os.File
+  err   error
+}
+
+func (s *store) fail(err error) {
+  if s != nil && s.err == nil {
+    s.err = err
+  }
+}
+
+// a temporary file, unlinked right away so closing it is enough to remove it
+func (s *store) temp() *os.File {
+  file, err := ioutil.TempFile("", "canopus-spill-")
+  if err != nil {
+    s.fail(err)
+    return nil
+  }
+  os.Remove(file.Name())
+  s.temps = append(s.temps, file)
+  return file
+}
+
+// close a temporary file the store no longer needs
+func (s *store) release(file *os.File) {
+  for i, f := range s.temps {
+    if f == file {
+      s.temps = append(s.temps[:i], s.temps[i+1:]...)
+      file.Close()
+      return
+    }
+  }
+}
+
+// close every temporary file of the store
+func (s *store) close() error {
+  var err error
+  for _, f := range s.temps {
+    e := f.Close()
+    if err == nil {
+      err = e
+    }
+  }
+  s.temps = nil
+  return err
+}
+
+// table maps keys to JSON encoded values, in a map or, for a store, in records
+// chained per bucket through a temporary file. A key put again keeps its last
+// value.
+type table struct {
+  values map[string][]byte
+  store  *store
+  file   *os.File
+  heads  []int64 // offset of the last record of each bucket, plus one
+  size   int64
+}
+
+func (s *store) table() *table {
+  if s == nil {
+    return &table{}
+  }
+  return &table{store: s, file: s.temp(), heads: make([]int64, tableBuckets)}
+}
+
+func (t *table) put(key string, value interface{}) {
+  data, err := json.Marshal(value)
+  if err != nil {
+    t.store.fail(err)
+    return
+  }
+  if t.file == nil {
+    if t.values == nil {
+      t.values = make(map[string][]byte)
+    }
+    t.values[key] = data
+    return
+  }
+  // previous record of the bucket, key length, value length, key, value
+  bucket := bucketOf(key)
+  record := make([]byte, 16, 16+len(key)+len(data))
+  binary.LittleEndian.PutUint64(record, uint64(t.heads[bucket]))
+  binary.LittleEndian.PutUint32(record[8:], uint32(len(key)))
+  binary.LittleEndian.PutUint32(record[12:], uint32(len(data)))
+  record = append(append(record, key...), data...)
+  _, err = t.file.WriteAt(record, t.size)
+  if err != nil {
+    t.store.fail(err)
+    return
+  }
+  t.heads[bucket] = t.size + 1
+  t.size += int64(len(record))
+}
+
+// decode the value of key into value, false if there is none
+func (t *table) get(key string, value interface{}) bool {
+  data, ok := t.lookup(key)
+  if !ok {
+    return false
+  }
+  err := json.Unmarshal(data, value)
+  if err != nil {
+    t.store.fail(err)
+    return false
+  }
+  return true
+}
+
+// true if the table has a value for key
+func (t *table) has(key string) bool {
+  _, ok := t.lookup(key)
+  return ok
+}
+
+// the last value put for key, walking its bucket from the newest record
+func (t *table) lookup(key string) ([]byte, bool) {
+  if t.file == nil {
+    data, ok := t.values[key]
+    return data, ok
+  }
+  header := make([]byte, 16)
+  next := t.heads[bucketOf(key)]
+  for next != 0 {
+    offset := next - 1
+    _, err := t.file.ReadAt(header, offset)
+    if err != nil {
+      t.store.fail(err)
+      return nil, false
+    }
+    next = int64(binary.LittleEndian.Uint64(header))
+    keyLen := int(binary.LittleEndian.Uint32(header[8:]))
+    if keyLen != len(key) {
+      continue
+    }
+    record := make([]byte, keyLen+int(binary.LittleEndian.Uint32(header[12:])))
+    _, err = t.file.ReadAt(record, offset+16)
+    if err != nil {
+      t.store.fail(err)
+      return nil, false
+    }
+    if string(record[:keyLen]) == key {
+      return record[keyLen:], true
+    }
+  }
+  return nil, false
+}
+
+func bucketOf(key string) int {
+  h := fnv.New32a()
+  h.Write([]byte(key))
+  return int(h.Sum32() % tableBuckets)
+}
+
+// list keeps JSON encoded values in the order they are added, in memory or,
+// for a store, in a temporary file
+type list struct {
+  values [][]byte
+  store  *store
+  file   *os.File
+  w      *bufio.Writer
+  size   int64
+  count  int
+}
+
+func (s *store) list() *list {
+  if s == nil {
+    return &list{}
+  }
+  l := &list{store: s, file: s.temp()}
+  if l.file != nil {
+    l.w = bufio.NewWriter(l.file)
+  }
+  return l
+}
+
+func (l *list) add(value interface{}) {
+  data, err := json.Marshal(value)
+  if err != nil {
+    l.store.fail(err)
+    return
+  }
+  l.count++
+  if l.file == nil {
+    l.values = append(l.values, data)
+    return
+  }
+  // length prefixed, write errors stay in the writer until it's flushed
+  var header [4]byte
+  binary.LittleEndian.PutUint32(header[:], uint32(len(data)))
+  l.w.Write(header[:])
+  l.w.Write(data)
+  l.size += int64(len(header) + len(data))
+}
+
+func (l *list) len() int {
+  if l == nil {
+    return 0
+  }
+  return l.count
+}
+
+// the values of a list of strings
+func (l *list) strings() []string {
+  var values []string
+  r := l.reader()
+  for {
+    value, ok := r.nextString()
+    if !ok {
+      return values
+    }
+    values = append(values, value)
+  }
+}
+
+// listReader reads the values of a list from the first
+type listReader struct {
+  l    *list
+  r    *bufio.Reader
+  read int
+}
+
+func (l *list) reader() *listReader {
+  if l == nil {
+    l = &list{}
+  }
+  r := &listReader{l: l}
+  if l.file != nil {
+    err := l.w.Flush()
+    if err != nil {
+      l.store.fail(err)
+    }
+    r.r = bufio.NewReader(io.NewSectionReader(l.file, 0, l.size))
+  }
+  return r
+}
+
+// the next JSON value, false after the last
+func (r *listReader) next() ([]byte, bool) {
+  if r.read >= r.l.count {
+    return nil, false
+  }
+  r.read++
+  if r.r == nil {
+    return r.l.values[r.read-1], true
+  }
+  var header [4]byte
+  _, err := io.ReadFull(r.r, header[:])
+  if err == nil {
+    data := make([]byte, binary.LittleEndian.Uint32(header[:]))
+    _, err = io.ReadFull(r.r, data)
+    if err == nil {
+      return data, true
+    }
+  }
+  r.l.store.fail(err)
+  r.read = r.l.count
+  return nil, false
+}
+
+func (r *listReader) nextString() (string, bool) {
+  data, ok := r.next()
+  if !ok {
+    return "", false
+  }
+  var value string
+  err := json.Unmarshal(data, &value)
+  if err != nil {
+    r.l.store.fail(err)
+    return "", false
+  }
+  return value, true
+}
+
+// a list of the strings of l in order. A disk list is sorted in runs of
+// sortRun strings, merged pairwise as runs of the same size pile up so only a
+// run per doubling stays open.
+func (s *store) sortStrings(l *list) *list {
+  if s == nil || l.file == nil {
+    values := l.strings()
+    sort.Strings(values)
+    sorted := &list{}
+    for _, value := range values {
+      sorted.add(value)
+    }
+    return sorted
+  }
+  var runs []*list
+  var sizes []int // in sortRun strings
+  r := l.reader()
+  for {
+    var values []string
+    for len(values) < sortRun {
+      value, ok := r.nextString()
+      if !ok {
+        break
+      }
+      values = append(values, value)
+    }
+    if len(values) == 0 {
+      break
+    }
+    sort.Strings(values)
+    run := s.list()
+    for _, value := range values {
+      run.add(value)
+    }
+    size := 1
+    for len(runs) > 0 && sizes[len(sizes)-1] == size {
+      run = s.merge(runs[len(runs)-1], run)
+      runs, sizes = runs[:len(runs)-1], sizes[:len(sizes)-1]
+      size *= 2
+    }
+    runs, sizes = append(runs, run), append(sizes, size)
+  }
+  sorted := s.list()
+  for i := len(runs) - 1; i >= 0; i-- {
+    sorted = s.merge(runs[i], sorted)
+  }
+  return sorted
+}
+
+// merge two sorted lists of strings into a new one, releasing both
+func (s *store) merge(a *list, b *list) *list {
+  merged := s.list()
+  ra, rb := a.reader(), b.reader()
+  x, okA := ra.nextString()
+  y, okB := rb.nextString()
+  for okA || okB {
+    if okA && (!okB || x <= y) {
+      merged.add(x)
+      x, okA = ra.nextString()
+    } else {
+      merged.add(y)
+      y, okB = rb.nextString()
+    }
+  }
+  s.release(a.file)
+  s.release(b.file)
+  return merged
+}
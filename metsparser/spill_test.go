@@ -0,0 +1,110 @@
+package metsparser
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "math/rand"
+  "reflect"
+  "sort"
+  "testing"
+)
+
+// more keys than buckets, so lookups walk chains, and keys put again
+func TestDiskTable(t *testing.T) {
+  s := &store{}
+  defer s.close()
+  table := s.table()
+  keys := 2 * tableBuckets
+  for i := 0; i < keys; i++ {
+    table.put(fmt.Sprint("file-", i), i)
+  }
+  for i := 0; i < keys; i += 7 {
+    table.put(fmt.Sprint("file-", i), -i)
+  }
+  for i := 0; i < keys; i++ {
+    want := i
+    if i%7 == 0 {
+      want = -i
+    }
+    var got int
+    if !table.get(fmt.Sprint("file-", i), &got) || got != want {
+      t.Fatalf("file-%d: got %d, want %d", i, got, want)
+    }
+  }
+  if table.has("file-") || table.has(fmt.Sprint("file-", keys)) {
+    t.Error("found a key never put")
+  }
+  if s.err != nil {
+    t.Fatal(s.err)
+  }
+}
+
+func TestSortStrings(t *testing.T) {
+  r := rand.New(rand.NewSource(1))
+  tests := []struct {
+    name  string
+    count int
+  }{
+    {"empty", 0},
+    {"one run", 10},
+    {"uneven runs", 5*sortRun + 17},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      var want []string
+      for i := 0; i < tt.count; i++ {
+        want = append(want, fmt.Sprintf("objects/%x.pdf", r.Intn(tt.count)))
+      }
+      for _, s := range []*store{nil, {}} {
+        l := s.list()
+        for _, value := range want {
+          l.add(value)
+        }
+        if !reflect.DeepEqual(l.strings(), want) {
+          t.Fatal("list not read back in order")
+        }
+        got := s.sortStrings(l).strings()
+        sorted := append([]string(nil), want...)
+        sort.Strings(sorted)
+        if !reflect.DeepEqual(got, sorted) {
+          t.Errorf("disk %v: sorted %d strings wrong", s != nil, len(got))
+        }
+        if s != nil {
+          if s.err != nil {
+            t.Fatal(s.err)
+          }
+          s.close()
+        }
+      }
+    })
+  }
+}
+
+// the lists WriteJSON reads back give the output json.MarshalIndent would
+func TestStreamedManifestWriteJSON(t *testing.T) {
+  s := &store{}
+  m := &StreamedManifest{store: s, sections: map[string]*list{SectionOriginals: s.list()}, rights: s.list(), sfErrors: s.list(), flagged: s.list()}
+  defer m.Close()
+  want := ObjectMetsManifest{Status: StatusWarnings, Quality: QualitySummary{Warnings: map[string]int64{}, Files: []string{}}}
+  for _, message := range []string{`a.pdf: format identification: Fail (<none> & "unknown")`, "b.pdf: format identification: Warning"} {
+    m.sfErrors.add(message)
+  }
+  want.SfErrors = `a.pdf: format identification: Fail (<none> & "unknown"); b.pdf: format identification: Warning`
+  file := FilesMets{FileName: "objects/a.pdf", Use: "original"}
+  m.sections[SectionOriginals].add(file)
+  want.Manifest.Files = []FilesMets{file}
+  m.ObjectMetsManifest = want
+  m.Manifest.Files = nil
+  m.SfErrors = ""
+
+  var output bytes.Buffer
+  err := m.WriteJSON(&output)
+  if err != nil {
+    t.Fatal(err)
+  }
+  expected, _ := json.MarshalIndent(want, "", "  ")
+  if !bytes.Equal(output.Bytes(), expected) {
+    t.Errorf("got:\n%s\nwant:\n%s", output.Bytes(), expected)
+  }
+}
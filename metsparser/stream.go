@@ -0,0 +1,298 @@
+package metsparser
+
+import (
+  "encoding/xml"
+  "io"
+  "strings"
+)
+
+// Decoder reads a METS document token by token instead of unmarshalling it
+// whole, so large AIP METS can be processed without holding the document or
+// its files in memory. What grows with the number of files, the index of IDs
+// per file, the events derivation relationships name, the files themselves and
+// the package lists that name files, is kept in temporary files, so memory
+// only grows with the size of one amdSec and the distinct agents, tools and
+// formats of the package.
+//
+// The first pass indexes the header, the dmdSec offsets, the fileSec, the
+// structMap and the links between files. The second pass decodes one amdSec
+// at a time and emits its file as soon as it's read. Descriptive metadata is
+// re-read from its recorded offset when a file needs it.
+type Decoder struct {
+  r    io.ReaderAt
+  size int64
+
+  store       *store
+  dmdSecs     *table // byte range keyed by dmdSec ID
+  dmdCount    int
+  files       *table // FileMapped keyed by file ID
+  admids      *table // file ID keyed by amdSec ID
+  structmap   *table // DMDIDs keyed by file ID
+  objects     []string
+  relations   *relations
+  packageName string
+  createDate  string
+}
+
+// NewDecoder returns a Decoder reading size bytes from r
+func NewDecoder(r io.ReaderAt, size int64) *Decoder {
+  return &Decoder{r: r, size: size}
+}
+
+// Stream calls fn, unless it's nil, with each file as it is resolved and
+// returns the package manifest, its files kept in temporary files until it's
+// written. Close the manifest once done with it.
+func (d *Decoder) Stream(opts Options, fn func(FilesMets) error) (*StreamedManifest, error) {
+  d.store = &store{}
+  m, err := d.stream(opts, fn)
+  if err == nil {
+    err = d.store.err
+  }
+  if err != nil {
+    d.store.close()
+    return nil, err
+  }
+  return m, nil
+}
+
+func (d *Decoder) stream(opts Options, fn func(FilesMets) error) (*StreamedManifest, error) {
+  err := d.index()
+  if err != nil {
+    return nil, err
+  }
+  if d.store.err != nil {
+    return nil, d.store.err
+  }
+  if d.dmdCount == 0 {
+    return nil, ErrMissingDmdSec
+  }
+
+  b, err := newBuilder(opts, d.store)
+  if err != nil {
+    return nil, err
+  }
+  b.objects = d.objects
+  b.fileMapped = d.fileMapped
+  b.packageName = d.packageName
+  b.createDate = d.createDate
+  b.dublincore = d.dublinCore
+  b.relations = d.relations
+
+  sections := make(map[string]*list)
+  dec := xml.NewDecoder(io.NewSectionReader(d.r, 0, d.size))
+  for {
+    tok, err := dec.Token()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return nil, &ParseError{Err: err}
+    }
+    start, ok := tok.(xml.StartElement)
+    if !ok || start.Name.Local != "amdSec" {
+      continue
+    }
+    a := AdminSec{}
+    err = dec.DecodeElement(&a, &start)
+    if err != nil {
+      return nil, &ParseError{Err: err}
+    }
+    file, err := b.file(a)
+    if err != nil {
+      return nil, err
+    }
+    if file == nil {
+      continue
+    }
+    section := UseSection(file.Use)
+    if sections[section] == nil {
+      sections[section] = d.store.list()
+    }
+    sections[section].add(file)
+    if fn != nil {
+      err = fn(*file)
+      if err != nil {
+        return nil, err
+      }
+    }
+  }
+
+  manifest, err := b.manifest()
+  if err != nil {
+    return nil, err
+  }
+  return &StreamedManifest{
+    ObjectMetsManifest: *manifest,
+    store:              d.store,
+    sections:           sections,
+    rights:             b.rights.rights,
+    sfErrors:           b.status.sfErrors,
+    flagged:            d.store.sortStrings(b.quality.flagged),
+  }, nil
+}
+
+// first pass: record everything needed to resolve a file from its amdSec
+func (d *Decoder) index() error {
+  d.dmdSecs = d.store.table()
+  d.files = d.store.table()
+  d.admids = d.store.table()
+  d.structmap = d.store.table()
+  d.relations = newRelations(d.store)
+
+  dec := xml.NewDecoder(io.NewSectionReader(d.r, 0, d.size))
+  for {
+    offset := dec.InputOffset()
+    tok, err := dec.Token()
+    if err == io.EOF {
+      return nil
+    }
+    if err != nil {
+      return &ParseError{Err: err}
+    }
+    start, ok := tok.(xml.StartElement)
+    if !ok {
+      continue
+    }
+    switch start.Name.Local {
+    case "metsHdr":
+      d.createDate = attr(start, "CREATEDATE")
+      err = dec.Skip()
+    case "dmdSec":
+      err = dec.Skip()
+      d.dmdSecs.put(attr(start, "ID"), []int64{offset, dec.InputOffset()})
+      d.dmdCount++
+    case "amdSec":
+      links := amdLinks{}
+      err = dec.DecodeElement(&links, &start)
+      d.relations.add(links)
+    case "fileGrp":
+      err = d.indexFileGrp(dec, attr(start, "USE"))
+    case "structMap":
+      if attr(start, "LABEL") == "Archivematica default" {
+        err = d.indexStructMap(dec)
+      } else {
+        err = dec.Skip()
+      }
+    }
+    if err != nil {
+      return &ParseError{Err: err}
+    }
+  }
+}
+
+// record the location and amdSec of every file in a fileGrp
+func (d *Decoder) indexFileGrp(dec *xml.Decoder, use string) error {
+  for {
+    tok, err := dec.Token()
+    if err != nil {
+      return err
+    }
+    switch t := tok.(type) {
+    case xml.StartElement:
+      if t.Name.Local != "file" {
+        continue
+      }
+      file := File{}
+      err = dec.DecodeElement(&file, &t)
+      if err != nil {
+        return err
+      }
+      filemapped := FileMapped{}
+      filemapped.Admid = file.Admid
+      filemapped.Name = file.FileLocation.Location
+      filemapped.Use = use
+      d.files.put(file.ID, filemapped)
+      d.admids.put(file.Admid, file.ID)
+    case xml.EndElement:
+      if t.Name.Local == "fileGrp" {
+        return nil
+      }
+    }
+  }
+}
+
+// walk the structMap divs the same way unpackDiv does, without holding the tree
+func (d *Decoder) indexStructMap(dec *xml.Decoder) error {
+  var stack []xml.StartElement
+  for {
+    tok, err := dec.Token()
+    if err != nil {
+      return err
+    }
+    switch t := tok.(type) {
+    case xml.StartElement:
+      if t.Name.Local == "fptr" && len(stack) > 0 {
+        div := stack[len(stack)-1]
+        if attr(div, "TYPE") == "Item" && directoriesOnly(stack[:len(stack)-1]) {
+          d.structmap.put(attr(t, "FILEID"), strings.Split(attr(div, "DMDID"), " "))
+        }
+      }
+      if t.Name.Local != "div" {
+        continue
+      }
+      if len(stack) == 0 && attr(t, "TYPE") == "Directory" {
+        d.packageName = attr(t, "LABEL")
+      }
+      if attr(t, "TYPE") == "Directory" && attr(t, "LABEL") == "objects" && directoriesOnly(stack) {
+        d.objects = strings.Split(attr(t, "DMDID"), " ")
+      }
+      stack = append(stack, t)
+    case xml.EndElement:
+      if t.Name.Local == "structMap" {
+        return nil
+      }
+      if t.Name.Local == "div" {
+        stack = stack[:len(stack)-1]
+      }
+    }
+  }
+}
+
+// the file an amdSec describes, if the structMap lists it
+func (d *Decoder) fileMapped(admid string) (FileMapped, bool) {
+  var id string
+  if !d.admids.get(admid, &id) {
+    return FileMapped{}, false
+  }
+  filemapped := FileMapped{}
+  var dmdids []string
+  if !d.files.get(id, &filemapped) || !d.structmap.get(id, &dmdids) {
+    return FileMapped{}, false
+  }
+  filemapped.Dmdid = dmdids
+  return filemapped, true
+}
+
+// re-read a dmdSec from its recorded offset
+func (d *Decoder) dublinCore(id string) (DescriptiveSec, bool) {
+  var s []int64 // start and end
+  if !d.dmdSecs.get(id, &s) || len(s) != 2 {
+    return DescriptiveSec{}, false
+  }
+  desc := DescriptiveSec{}
+  err := xml.NewDecoder(io.NewSectionReader(d.r, s[0], s[1]-s[0])).Decode(&desc)
+  if err != nil || desc.Dmd.Mdtype != "DC" {
+    return DescriptiveSec{}, false
+  }
+  return desc, true
+}
+
+// true if every div is a Directory, as unpackDiv only recurses into those
+func directoriesOnly(divs []xml.StartElement) bool {
+  for _, div := range divs {
+    if attr(div, "TYPE") != "Directory" {
+      return false
+    }
+  }
+  return true
+}
+
+// return the value of an attribute by local name
+func attr(start xml.StartElement, name string) string {
+  for _, a := range start.Attr {
+    if a.Name.Local == name {
+      return a.Value
+    }
+  }
+  return ""
+}
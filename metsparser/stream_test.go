@@ -0,0 +1,70 @@
+package metsparser
+
+import (
+  "bytes"
+  "encoding/json"
+  "io/ioutil"
+  "testing"
+  "time"
+)
+
+// the streaming decoder must produce the manifest BuildManifest does
+func TestStreamMatchesBuildManifest(t *testing.T) {
+  rightsDate := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+  options := []struct {
+    name string
+    opts Options
+  }{
+    {"default", Options{RightsDate: rightsDate}},
+    {"embedded agents and history", Options{RightsDate: rightsDate, EmbedAgents: true, DescriptiveHistory: true}},
+    {"originals only", Options{RightsDate: rightsDate, IncludeUse: []string{"original"}}},
+    {"format policy", Options{RightsDate: rightsDate, FormatPolicy: &FormatPolicy{Formats: map[string]FormatRule{"x-fmt/111": {Risk: "high"}}}}},
+  }
+  for _, fixture := range []string{"METS.abc.xml", "METS.supporting.xml", "METS.repeated-bag-info.xml"} {
+    data, err := ioutil.ReadFile("testdata/" + fixture)
+    if err != nil {
+      t.Fatal(err)
+    }
+    for _, o := range options {
+      t.Run(fixture+"/"+o.name, func(t *testing.T) {
+        mets, err := Parse(bytes.NewReader(data))
+        if err != nil {
+          t.Fatal(err)
+        }
+        built, err := BuildManifest(mets, o.opts)
+        if err != nil {
+          t.Fatal(err)
+        }
+        if len(built.Manifest.AllFiles()) == 0 {
+          t.Fatal("no files in the manifest")
+        }
+
+        var files ManifestMets
+        streamed, err := NewDecoder(bytes.NewReader(data), int64(len(data))).Stream(o.opts, func(f FilesMets) error {
+          files.Add(f)
+          return nil
+        })
+        if err != nil {
+          t.Fatal(err)
+        }
+        defer streamed.Close()
+        passed, _ := json.Marshal(files)
+        listed, _ := json.Marshal(ManifestMets{Files: built.Manifest.Files, Derivatives: built.Manifest.Derivatives, Supporting: built.Manifest.Supporting})
+        if !bytes.Equal(passed, listed) {
+          t.Errorf("files passed to fn differ:\n%s\nwant:\n%s", passed, listed)
+        }
+
+        want, _ := json.MarshalIndent(built, "", "  ")
+        var output bytes.Buffer
+        err = streamed.WriteJSON(&output)
+        if err != nil {
+          t.Fatal(err)
+        }
+        got := output.Bytes()
+        if !bytes.Equal(got, want) {
+          t.Errorf("streamed manifest differs:\n%s\nwant:\n%s", got, want)
+        }
+      })
+    }
+  }
+}
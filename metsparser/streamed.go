@@ -0,0 +1,165 @@
+package metsparser
+
+import (
+  "bufio"
+  "bytes"
+  "encoding/json"
+  "io"
+  "reflect"
+)
+
+// StreamedManifest is the package manifest Decoder.Stream returns. Its files,
+// package rights, format identification problems and files with quality
+// warnings stay in temporary files, out of the embedded ObjectMetsManifest,
+// until WriteJSON reads them back.
+type StreamedManifest struct {
+  ObjectMetsManifest
+  store    *store
+  sections map[string]*list // files keyed by manifest section
+  rights   *list
+  sfErrors *list
+  flagged  *list // sorted
+}
+
+// WriteJSON writes the manifest as json.MarshalIndent(m, "", "  ") writes the
+// one BuildManifest returns
+func (m *StreamedManifest) WriteJSON(w io.Writer) error {
+  bw := bufio.NewWriter(w)
+  err := writeObject(bw, reflect.ValueOf(m.ObjectMetsManifest), "", func(name string, v reflect.Value, prefix string) (bool, error) {
+    switch name {
+    case "manifest":
+      return true, writeObject(bw, v, prefix, func(section string, _ reflect.Value, prefix string) (bool, error) {
+        files, ok := m.sections[section]
+        if !ok {
+          return false, nil
+        }
+        return true, writeList(bw, files, prefix, "null")
+      })
+    case "rights":
+      return true, writeList(bw, m.rights, prefix, "null")
+    case "sf_errors":
+      // the problems joined with "; ", each already a JSON string
+      bw.WriteString(`"`)
+      r := m.sfErrors.reader()
+      for i := 0; ; i++ {
+        data, ok := r.next()
+        if !ok {
+          break
+        }
+        if i > 0 {
+          bw.WriteString("; ")
+        }
+        bw.Write(data[1 : len(data)-1])
+      }
+      bw.WriteString(`"`)
+      return true, nil
+    case "quality":
+      return true, writeObject(bw, v, prefix, func(name string, _ reflect.Value, prefix string) (bool, error) {
+        if name != "files" {
+          return false, nil
+        }
+        return true, writeList(bw, m.flagged, prefix, "[]")
+      })
+    }
+    return false, nil
+  })
+  if err == nil {
+    err = bw.Flush()
+  }
+  if err == nil {
+    err = m.store.err
+  }
+  return err
+}
+
+// Close removes the temporary files of the manifest
+func (m *StreamedManifest) Close() error {
+  return m.store.close()
+}
+
+// write a struct as json.MarshalIndent would with prefix, field by field;
+// field writes the values it handles itself, prefixed for their depth, and
+// returns false for the others
+func writeObject(w *bufio.Writer, v reflect.Value, prefix string, field func(name string, v reflect.Value, prefix string) (bool, error)) error {
+  w.WriteString("{")
+  first := true
+  for i := 0; i < v.NumField(); i++ {
+    name, omitEmpty := jsonFieldName(v.Type().Field(i))
+    if name == "" || (omitEmpty && isEmptyValue(v.Field(i))) {
+      continue
+    }
+    if !first {
+      w.WriteString(",")
+    }
+    first = false
+    key, _ := json.Marshal(name)
+    w.WriteString("\n" + prefix + "  ")
+    w.Write(key)
+    w.WriteString(": ")
+    done, err := field(name, v.Field(i), prefix+"  ")
+    if err != nil {
+      return err
+    }
+    if done {
+      continue
+    }
+    value, err := json.MarshalIndent(v.Field(i).Interface(), prefix+"  ", "  ")
+    if err != nil {
+      return err
+    }
+    w.Write(value)
+  }
+  if !first {
+    w.WriteString("\n" + prefix)
+  }
+  _, err := w.WriteString("}")
+  return err
+}
+
+// write the values of a list as a JSON array, empty when it has none
+func writeList(w *bufio.Writer, l *list, prefix string, empty string) error {
+  if l.len() == 0 {
+    _, err := w.WriteString(empty)
+    return err
+  }
+  w.WriteString("[")
+  r := l.reader()
+  var value bytes.Buffer
+  for i := 0; ; i++ {
+    data, ok := r.next()
+    if !ok {
+      break
+    }
+    if i > 0 {
+      w.WriteString(",")
+    }
+    w.WriteString("\n" + prefix + "  ")
+    value.Reset()
+    err := json.Indent(&value, data, prefix+"  ", "  ")
+    if err != nil {
+      return err
+    }
+    w.Write(value.Bytes())
+  }
+  _, err := w.WriteString("\n" + prefix + "]")
+  return err
+}
+
+// the values omitempty leaves out, as encoding/json defines them
+func isEmptyValue(v reflect.Value) bool {
+  switch v.Kind() {
+  case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+    return v.Len() == 0
+  case reflect.Bool:
+    return !v.Bool()
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    return v.Int() == 0
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+    return v.Uint() == 0
+  case reflect.Float32, reflect.Float64:
+    return v.Float() == 0
+  case reflect.Interface, reflect.Ptr:
+    return v.IsNil()
+  }
+  return false
+}
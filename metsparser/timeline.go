@@ -0,0 +1,157 @@
+package metsparser
+
+import (
+  "sort"
+  "time"
+)
+
+// TimelineReport orders the events of every file of a package in time and
+// measures how long ingest took, per file and per event type
+type TimelineReport struct {
+  Package         string              `json:"package"`
+  Start           string              `json:"start"`
+  End             string              `json:"end"`
+  DurationSeconds float64             `json:"duration_seconds"`
+  Files           []FileTimeline      `json:"files"`
+  EventTypes      []EventTypeDuration `json:"event_types"`
+}
+
+// FileTimeline is the events of one file in chronological order. Events
+// whose date can't be read are listed last, in document order.
+type FileTimeline struct {
+  FileName        string          `json:"filename"`
+  Start           string          `json:"start"`
+  End             string          `json:"end"`
+  DurationSeconds float64         `json:"duration_seconds"`
+  Events          []TimelineEvent `json:"events"`
+}
+
+// TimelineEvent is an event with the time since the file's previous event,
+// taken as the time spent reaching it
+type TimelineEvent struct {
+  Uuid          string  `json:"uuid"`
+  Type          string  `json:"type"`
+  DateTime      string  `json:"datetime"`
+  Outcome       string  `json:"outcome"`
+  SincePrevious float64 `json:"since_previous_seconds"`
+  Undated       bool    `json:"undated,omitempty"`
+}
+
+// EventTypeDuration sums the time spent reaching events of one type across
+// the package, slowest types first
+type EventTypeDuration struct {
+  Type         string  `json:"type"`
+  Count        int     `json:"count"`
+  TotalSeconds float64 `json:"total_seconds"`
+  MeanSeconds  float64 `json:"mean_seconds"`
+  MaxSeconds   float64 `json:"max_seconds"`
+}
+
+// Timeline builds the timeline report of a manifest
+func Timeline(m *ObjectMetsManifest) TimelineReport {
+  report := TimelineReport{Package: m.StorageLocation, Files: []FileTimeline{}, EventTypes: []EventTypeDuration{}}
+  var start, end time.Time
+  types := make(map[string]*EventTypeDuration)
+
+  for _, file := range m.Manifest.AllFiles() {
+    timeline, first, last := fileTimeline(file)
+    report.Files = append(report.Files, timeline)
+    if !first.IsZero() && (start.IsZero() || first.Before(start)) {
+      start = first
+    }
+    if !last.IsZero() && last.After(end) {
+      end = last
+    }
+    for i, e := range timeline.Events {
+      // the first event of a file has nothing to be measured from
+      if i == 0 || e.Undated {
+        continue
+      }
+      t, ok := types[e.Type]
+      if !ok {
+        t = &EventTypeDuration{Type: e.Type}
+        types[e.Type] = t
+      }
+      t.Count++
+      t.TotalSeconds += e.SincePrevious
+      if e.SincePrevious > t.MaxSeconds {
+        t.MaxSeconds = e.SincePrevious
+      }
+    }
+  }
+
+  if !start.IsZero() {
+    report.Start = start.Format(time.RFC3339Nano)
+    report.End = end.Format(time.RFC3339Nano)
+    report.DurationSeconds = end.Sub(start).Seconds()
+  }
+  for _, t := range types {
+    t.MeanSeconds = t.TotalSeconds / float64(t.Count)
+    report.EventTypes = append(report.EventTypes, *t)
+  }
+  sort.Slice(report.EventTypes, func(i, j int) bool {
+    a, b := report.EventTypes[i], report.EventTypes[j]
+    if a.TotalSeconds != b.TotalSeconds {
+      return a.TotalSeconds > b.TotalSeconds
+    }
+    return a.Type < b.Type
+  })
+  return report
+}
+
+// sort the events of a file and return the times of its first and last dated event
+func fileTimeline(file *FilesMets) (FileTimeline, time.Time, time.Time) {
+  type dated struct {
+    event Events
+    when  time.Time
+    ok    bool
+  }
+  var events []dated
+  for _, e := range file.DescriptiveMD.Events {
+    when, err := ParseEventDate(e.DateTime)
+    events = append(events, dated{e, when, err == nil})
+  }
+  sort.SliceStable(events, func(i, j int) bool {
+    if events[i].ok != events[j].ok {
+      return events[i].ok
+    }
+    return events[i].ok && events[i].when.Before(events[j].when)
+  })
+
+  timeline := FileTimeline{FileName: file.FileName, Events: []TimelineEvent{}}
+  var first, last time.Time
+  for _, d := range events {
+    e := TimelineEvent{Uuid: d.event.Uuid, Type: d.event.Type, DateTime: d.event.DateTime, Outcome: d.event.Outcome, Undated: !d.ok}
+    if d.ok {
+      if first.IsZero() {
+        first = d.when
+      } else {
+        e.SincePrevious = d.when.Sub(last).Seconds()
+      }
+      last = d.when
+    }
+    timeline.Events = append(timeline.Events, e)
+  }
+  if !first.IsZero() {
+    timeline.Start = first.Format(time.RFC3339Nano)
+    timeline.End = last.Format(time.RFC3339Nano)
+    timeline.DurationSeconds = last.Sub(first).Seconds()
+  }
+  return timeline, first, last
+}
+
+// ParseEventDate reads a PREMIS eventDateTime, as Archivematica writes it
+// (2020-05-01T10:02:00.000000+00:00) or without a time zone, taken as UTC
+func ParseEventDate(value string) (time.Time, error) {
+  t, err := time.Parse(time.RFC3339Nano, value)
+  if err == nil {
+    return t, nil
+  }
+  for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02"} {
+    t, e := time.Parse(layout, value)
+    if e == nil {
+      return t, nil
+    }
+  }
+  return t, err
+}
@@ -0,0 +1,118 @@
+package metsparser
+
+import (
+  "reflect"
+  "testing"
+  "time"
+)
+
+func TestParseEventDate(t *testing.T) {
+  tests := []struct {
+    value string
+    want  time.Time
+    ok    bool
+  }{
+    {"2020-05-01T10:02:00.000000+00:00", time.Date(2020, 5, 1, 10, 2, 0, 0, time.UTC), true},
+    {"2020-05-01T12:02:00.5+02:00", time.Date(2020, 5, 1, 10, 2, 0, 500000000, time.UTC), true},
+    // no time zone is taken as UTC
+    {"2020-05-01T10:02:00.123456", time.Date(2020, 5, 1, 10, 2, 0, 123456000, time.UTC), true},
+    {"2020-05-01 10:02:00+00:00", time.Date(2020, 5, 1, 10, 2, 0, 0, time.UTC), true},
+    {"2020-05-01", time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC), true},
+    {"", time.Time{}, false},
+    {"01/05/2020", time.Time{}, false},
+  }
+  for _, tt := range tests {
+    got, err := ParseEventDate(tt.value)
+    if (err == nil) != tt.ok {
+      t.Errorf("%q: error %v, want ok %v", tt.value, err, tt.ok)
+      continue
+    }
+    if tt.ok && !got.Equal(tt.want) {
+      t.Errorf("%q: got %v, want %v", tt.value, got, tt.want)
+    }
+  }
+}
+
+func timelineFile(name string, events ...Events) FilesMets {
+  file := FilesMets{FileName: name}
+  file.DescriptiveMD.Events = events
+  return file
+}
+
+func TestFileTimeline(t *testing.T) {
+  file := timelineFile("objects/a.pdf",
+    Events{Uuid: "3", Type: "normalization", DateTime: "2020-05-01T10:04:00+00:00"},
+    Events{Uuid: "u", Type: "format identification", DateTime: "unknown"},
+    Events{Uuid: "1", Type: "ingestion", DateTime: "2020-05-01T10:00:00+00:00"},
+    Events{Uuid: "2", Type: "virus check", DateTime: "2020-05-01T10:01:00+00:00"},
+  )
+  timeline, first, last := fileTimeline(&file)
+
+  var order []string
+  var since []float64
+  for _, e := range timeline.Events {
+    order = append(order, e.Uuid)
+    since = append(since, e.SincePrevious)
+  }
+  // undated events go last, measured from nothing
+  if !reflect.DeepEqual(order, []string{"1", "2", "3", "u"}) {
+    t.Errorf("events in order %v", order)
+  }
+  if !reflect.DeepEqual(since, []float64{0, 60, 180, 0}) {
+    t.Errorf("seconds since previous %v", since)
+  }
+  if !timeline.Events[3].Undated || timeline.Events[2].Undated {
+    t.Error("only the last event should be undated")
+  }
+  if !first.Equal(time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)) || !last.Equal(time.Date(2020, 5, 1, 10, 4, 0, 0, time.UTC)) {
+    t.Errorf("first %v, last %v", first, last)
+  }
+  if timeline.Start != "2020-05-01T10:00:00Z" || timeline.End != "2020-05-01T10:04:00Z" || timeline.DurationSeconds != 240 {
+    t.Errorf("start %s, end %s, duration %v", timeline.Start, timeline.End, timeline.DurationSeconds)
+  }
+
+  undated := timelineFile("objects/b.pdf", Events{Uuid: "x", DateTime: ""})
+  timeline, first, _ = fileTimeline(&undated)
+  if !first.IsZero() || timeline.Start != "" || timeline.DurationSeconds != 0 {
+    t.Errorf("file without dated events has start %q, duration %v", timeline.Start, timeline.DurationSeconds)
+  }
+}
+
+func TestTimeline(t *testing.T) {
+  m := &ObjectMetsManifest{StorageLocation: "pkg"}
+  m.Manifest.Files = []FilesMets{
+    timelineFile("objects/a.pdf",
+      Events{Type: "ingestion", DateTime: "2020-05-01T10:00:00+00:00"},
+      Events{Type: "virus check", DateTime: "2020-05-01T10:01:00+00:00"},
+      Events{Type: "normalization", DateTime: "2020-05-01T10:04:00+00:00"},
+    ),
+  }
+  m.Manifest.Supporting = []FilesMets{
+    timelineFile("objects/submissionDocumentation/b.txt",
+      Events{Type: "ingestion", DateTime: "2020-05-01T10:00:30+00:00"},
+      Events{Type: "virus check", DateTime: "2020-05-01T10:02:30+00:00"},
+      Events{Type: "format identification", DateTime: ""},
+    ),
+  }
+  report := Timeline(m)
+
+  if report.Package != "pkg" || len(report.Files) != 2 {
+    t.Fatalf("package %q with %d files", report.Package, len(report.Files))
+  }
+  if report.Start != "2020-05-01T10:00:00Z" || report.End != "2020-05-01T10:04:00Z" || report.DurationSeconds != 240 {
+    t.Errorf("start %s, end %s, duration %v", report.Start, report.End, report.DurationSeconds)
+  }
+  // first events and undated ones aren't counted; equal totals sort by type
+  want := []EventTypeDuration{
+    {Type: "normalization", Count: 1, TotalSeconds: 180, MeanSeconds: 180, MaxSeconds: 180},
+    {Type: "virus check", Count: 2, TotalSeconds: 180, MeanSeconds: 90, MaxSeconds: 120},
+  }
+  if !reflect.DeepEqual(report.EventTypes, want) {
+    t.Errorf("event types %+v, want %+v", report.EventTypes, want)
+  }
+
+  empty := Timeline(&ObjectMetsManifest{})
+  if empty.Start != "" || len(empty.Files) != 0 || empty.EventTypes == nil {
+    t.Errorf("empty manifest report %+v", empty)
+  }
+}
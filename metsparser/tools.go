@@ -0,0 +1,135 @@
+package metsparser
+
+import (
+  "sort"
+  "strings"
+)
+
+// where a tool was found
+const (
+  ToolSourceEvent = "event"
+  ToolSourceFits  = "fits"
+)
+
+// tools and versions seen in a package, with the files each one processed
+type toolInventory struct {
+  tools map[string]*ToolUsage
+  files map[string]int64 // files counted per tool, so one file counts once
+}
+
+func toolKey(program string, version string) string {
+  return strings.ToLower(program) + "\x00" + version
+}
+
+// record the tools that performed the events of a file, and those FITS ran on it
+func (inv *toolInventory) add(fileNumber int64, events []Events, fits Fits) {
+  if inv.tools == nil {
+    inv.tools = make(map[string]*ToolUsage)
+    inv.files = make(map[string]int64)
+  }
+  for _, e := range events {
+    if e.Tool == nil || e.Tool.Program == "" {
+      continue
+    }
+    inv.use(fileNumber, e.Tool.Program, e.Tool.Version, ToolSourceEvent, e.Type)
+  }
+  for _, identity := range fits.Identification.Identities {
+    if identity.Toolname != "" {
+      inv.use(fileNumber, identity.Toolname, identity.Toolversion, ToolSourceFits, "")
+    }
+    for _, tool := range identity.Tools {
+      if tool.Toolname != "" {
+        inv.use(fileNumber, tool.Toolname, tool.Toolversion, ToolSourceFits, "")
+      }
+    }
+  }
+}
+
+func (inv *toolInventory) use(fileNumber int64, program string, version string, source string, eventType string) {
+  key := toolKey(program, version)
+  tool, ok := inv.tools[key]
+  if !ok {
+    tool = &ToolUsage{Program: program, Version: version, Sources: []string{}, EventTypes: []string{}}
+    inv.tools[key] = tool
+  }
+  tool.Sources = addUnique(tool.Sources, source)
+  if eventType != "" {
+    tool.EventTypes = addUnique(tool.EventTypes, eventType)
+  }
+  // files are numbered from 1 in the order they are resolved
+  if inv.files[key] != fileNumber {
+    inv.files[key] = fileNumber
+    tool.Files++
+  }
+}
+
+// the tools sorted by program and version
+func (inv *toolInventory) list() []ToolUsage {
+  var tools []ToolUsage
+  for _, tool := range inv.tools {
+    tools = append(tools, *tool)
+  }
+  sortTools(tools)
+  return tools
+}
+
+func sortTools(tools []ToolUsage) {
+  sort.Slice(tools, func(i, j int) bool {
+    a, b := strings.ToLower(tools[i].Program), strings.ToLower(tools[j].Program)
+    if a != b {
+      return a < b
+    }
+    return tools[i].Version < tools[j].Version
+  })
+}
+
+func addUnique(values []string, value string) []string {
+  for _, v := range values {
+    if v == value {
+      return values
+    }
+  }
+  return append(values, value)
+}
+
+// ToolReport is the tool inventory of many packages
+type ToolReport struct {
+  Packages []string    `json:"packages"`
+  Tools    []ToolUsage `json:"tools"`
+
+  merged map[string]*ToolUsage
+}
+
+// Add merges the tool inventory of a manifest into the report: a tool's file
+// counts add up and its Packages counts the manifests listing it. The report
+// keeps the merged tools, not the manifest.
+func (r *ToolReport) Add(m *ObjectMetsManifest) {
+  if r.merged == nil {
+    r.merged = make(map[string]*ToolUsage)
+  }
+  if r.Packages == nil {
+    r.Packages = []string{}
+  }
+  r.Packages = append(r.Packages, m.StorageLocation)
+  for _, t := range m.Tools {
+    key := toolKey(t.Program, t.Version)
+    tool, ok := r.merged[key]
+    if !ok {
+      tool = &ToolUsage{Program: t.Program, Version: t.Version, Sources: []string{}, EventTypes: []string{}}
+      r.merged[key] = tool
+    }
+    for _, source := range t.Sources {
+      tool.Sources = addUnique(tool.Sources, source)
+    }
+    for _, eventType := range t.EventTypes {
+      tool.EventTypes = addUnique(tool.EventTypes, eventType)
+    }
+    tool.Files += t.Files
+    tool.Packages++
+  }
+  r.Tools = []ToolUsage{}
+  for _, tool := range r.merged {
+    r.Tools = append(r.Tools, *tool)
+  }
+  sortTools(r.Tools)
+}
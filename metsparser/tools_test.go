@@ -0,0 +1,40 @@
+package metsparser
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestToolReportAdd(t *testing.T) {
+  first := &ObjectMetsManifest{StorageLocation: "pkg-1", Tools: []ToolUsage{
+    {Program: "Siegfried", Version: "1.9.1", Sources: []string{ToolSourceEvent}, EventTypes: []string{"format identification"}, Files: 3},
+    {Program: "ClamAV", Version: "0.103", Sources: []string{ToolSourceEvent}, EventTypes: []string{"virus check"}, Files: 3},
+  }}
+  second := &ObjectMetsManifest{StorageLocation: "pkg-2", Tools: []ToolUsage{
+    // the program name is matched regardless of case, the version exactly
+    {Program: "clamav", Version: "0.103", Sources: []string{ToolSourceFits, ToolSourceEvent}, EventTypes: []string{"virus check"}, Files: 2},
+    {Program: "ClamAV", Version: "0.104", Sources: []string{ToolSourceEvent}, EventTypes: []string{"virus check"}, Files: 1},
+    {Program: "Jhove", Version: "1.20.1", Sources: []string{ToolSourceFits}, EventTypes: []string{}, Files: 2},
+  }}
+
+  var report ToolReport
+  report.Add(&ObjectMetsManifest{StorageLocation: "empty"})
+  if report.Tools == nil || len(report.Tools) != 0 {
+    t.Errorf("tools %v after a manifest without tools, want an empty list", report.Tools)
+  }
+  report.Add(first)
+  report.Add(second)
+
+  if !reflect.DeepEqual(report.Packages, []string{"empty", "pkg-1", "pkg-2"}) {
+    t.Errorf("packages %v", report.Packages)
+  }
+  want := []ToolUsage{
+    {Program: "ClamAV", Version: "0.103", Sources: []string{ToolSourceEvent, ToolSourceFits}, EventTypes: []string{"virus check"}, Files: 5, Packages: 2},
+    {Program: "ClamAV", Version: "0.104", Sources: []string{ToolSourceEvent}, EventTypes: []string{"virus check"}, Files: 1, Packages: 1},
+    {Program: "Jhove", Version: "1.20.1", Sources: []string{ToolSourceFits}, EventTypes: []string{}, Files: 2, Packages: 1},
+    {Program: "Siegfried", Version: "1.9.1", Sources: []string{ToolSourceEvent}, EventTypes: []string{"format identification"}, Files: 3, Packages: 1},
+  }
+  if !reflect.DeepEqual(report.Tools, want) {
+    t.Errorf("tools\n%+v\nwant\n%+v", report.Tools, want)
+  }
+}
@@ -0,0 +1,132 @@
+package metsparser
+
+import (
+  "fmt"
+  "regexp"
+  "strings"
+)
+
+// TransferMapping names the bag-info (transfer_metadata) fields used to fill
+// manifest fields. An empty name leaves the manifest field unset.
+type TransferMapping struct {
+  DepositorName       string `json:"depositor_name"`
+  DepartmentOrLibrary string `json:"department_or_library"`
+  JiraTicketNumber    string `json:"jira_ticket_number"`
+  // regular expression applied to the ticket field, the first group is kept if
+  // there is one, otherwise the whole match
+  JiraTicketPattern   string `json:"jira_ticket_pattern"`
+  BaggingDate         string `json:"bagging_date"`
+}
+
+// DefaultTransferMapping returns the mapping used when Options has none
+func DefaultTransferMapping() TransferMapping {
+  return TransferMapping{
+    DepositorName:       "Contact-Name",
+    DepartmentOrLibrary: "Source-Organization",
+    JiraTicketNumber:    "External-Identifier",
+    JiraTicketPattern:   `\b[A-Z][A-Z0-9]+-[0-9]+\b`,
+    BaggingDate:         "Bagging-Date",
+  }
+}
+
+// bag-info values collected from every sourceMD in the package
+type transferMetadata struct {
+  mapping TransferMapping
+  ticket  *regexp.Regexp
+  values  map[string]string
+  seen    map[string]bool // bag-infos already added
+  sources []SourceMD      // each transfer declaring bag totals, once
+}
+
+func newTransferMetadata(mapping *TransferMapping) (*transferMetadata, error) {
+  t := &transferMetadata{mapping: DefaultTransferMapping()}
+  if mapping != nil {
+    t.mapping = *mapping
+  }
+  if t.mapping.JiraTicketPattern != "" {
+    ticket, err := regexp.Compile(t.mapping.JiraTicketPattern)
+    if err != nil {
+      return nil, fmt.Errorf("jira ticket pattern: %w", err)
+    }
+    t.ticket = ticket
+  }
+  return t, nil
+}
+
+// add the bag-info fields of a sourceMD. The same bag-info recorded in several
+// amdSecs is added once, and a repeated field joins the values that differ
+func (t *transferMetadata) add(s SourceMD) error {
+  fields, err := s.BagInfo()
+  if err != nil {
+    return err
+  }
+  if len(fields) == 0 {
+    return nil
+  }
+  key := bagInfoKey(fields)
+  if t.seen == nil {
+    t.seen = make(map[string]bool)
+    t.values = make(map[string]string)
+  }
+  if t.seen[key] {
+    return nil
+  }
+  t.seen[key] = true
+  if s.Payload != "" || s.BagSize != "" {
+    t.sources = append(t.sources, s)
+  }
+  for _, f := range fields {
+    name := f.XMLName.Local
+    value := strings.TrimSpace(f.Value)
+    current, ok := t.values[name]
+    if !ok {
+      t.values[name] = value
+    } else if !containsValue(strings.Split(current, ","), value) {
+      t.values[name] += "," + value
+    }
+  }
+  return nil
+}
+
+// a bag-info by its fields, whatever the whitespace around them
+func bagInfoKey(fields []BagInfoField) string {
+  var key strings.Builder
+  for _, f := range fields {
+    key.WriteString(f.XMLName.Local + "=" + strings.TrimSpace(f.Value) + "\n")
+  }
+  return key.String()
+}
+
+func containsValue(values []string, value string) bool {
+  for _, v := range values {
+    if v == value {
+      return true
+    }
+  }
+  return false
+}
+
+// fill the manifest fields from the mapping
+func (t *transferMetadata) apply(m *ObjectMetsManifest) {
+  m.TransferMetadata = t.values
+  if t.values == nil {
+    return
+  }
+  m.DepositorName = t.values[t.mapping.DepositorName]
+  m.DepartmentOrLibrary = t.values[t.mapping.DepartmentOrLibrary]
+  ticket := t.values[t.mapping.JiraTicketNumber]
+  if t.ticket != nil {
+    match := t.ticket.FindStringSubmatch(ticket)
+    ticket = ""
+    if len(match) > 1 {
+      ticket = match[1]
+    } else if len(match) == 1 {
+      ticket = match[0]
+    }
+  }
+  m.JiraTicketNumber = ticket
+  baggingDate := t.values[t.mapping.BaggingDate]
+  if baggingDate != "" {
+    m.BaggingDate = baggingDate
+  }
+}
@@ -0,0 +1,181 @@
+package metsparser
+
+import (
+  "fmt"
+  "strings"
+)
+
+// kinds of referential integrity problems
+const (
+  ProblemDuplicateID        = "duplicate_id"
+  ProblemDanglingAdmid      = "dangling_admid"
+  ProblemDanglingDmdid      = "dangling_dmdid"
+  ProblemDanglingFileid     = "dangling_fileid"
+  ProblemOrphanedAmdSec     = "orphaned_amdsec"
+  ProblemOrphanedDmdSec     = "orphaned_dmdsec"
+  ProblemFileNotInStructMap = "file_not_in_structmap"
+)
+
+// IntegrityReport lists the broken ID references found in a METS
+type IntegrityReport struct {
+  Valid    bool               `json:"valid"`
+  Problems []IntegrityProblem `json:"problems"`
+}
+
+// IntegrityProblem is one broken reference, ID is the identifier at fault and
+// Element where it was found
+type IntegrityProblem struct {
+  Kind    string `json:"kind"`
+  ID      string `json:"id"`
+  Element string `json:"element"`
+  Message string `json:"message"`
+}
+
+// Validate cross-checks every ADMID, DMDID and FILEID reference between the
+// structMap, fileSec, amdSec and dmdSec elements
+func Validate(mets *Mets) IntegrityReport {
+  v := validator{ids: make(map[string]string), problems: []IntegrityProblem{}}
+
+  dmdSecs := make(map[string]bool)
+  for _, d := range mets.DescriptiveSec {
+    v.declare(d.ID, "dmdSec")
+    dmdSecs[d.ID] = true
+  }
+  amdIds := make(map[string]bool) // amdSecs and the metadata sections inside them
+  for _, a := range mets.AdminSec {
+    v.declare(a.ID, "amdSec")
+    amdIds[a.ID] = true
+    for _, id := range amdChildIds(a) {
+      v.declare(id, "amdSec "+a.ID)
+      amdIds[id] = true
+    }
+  }
+  files := make(map[string]bool)
+  for _, grp := range mets.FileSec.FileGrp {
+    for _, file := range grp.Files {
+      v.declare(file.ID, "fileSec")
+      files[file.ID] = true
+    }
+  }
+  for _, sm := range mets.StructMap {
+    v.declare(sm.ID, "structMap")
+  }
+
+  admidUsed := make(map[string]bool)
+  dmdidUsed := make(map[string]bool)
+  fileidUsed := make(map[string]bool)
+
+  for _, grp := range mets.FileSec.FileGrp {
+    for _, file := range grp.Files {
+      for _, id := range splitIds(file.Admid) {
+        admidUsed[id] = true
+        if !amdIds[id] {
+          v.problem(ProblemDanglingAdmid, id, "file "+file.ID, "ADMID does not match any amdSec")
+        }
+      }
+    }
+  }
+
+  var walk func(div Div, where string)
+  walk = func(div Div, where string) {
+    where += "/" + div.Label
+    for _, id := range splitIds(div.Admid) {
+      admidUsed[id] = true
+      if !amdIds[id] {
+        v.problem(ProblemDanglingAdmid, id, "div "+where, "ADMID does not match any amdSec")
+      }
+    }
+    for _, id := range splitIds(div.Dmdid) {
+      dmdidUsed[id] = true
+      if !dmdSecs[id] {
+        v.problem(ProblemDanglingDmdid, id, "div "+where, "DMDID does not match any dmdSec")
+      }
+    }
+    if div.File.Fileid != "" {
+      fileidUsed[div.File.Fileid] = true
+      if !files[div.File.Fileid] {
+        v.problem(ProblemDanglingFileid, div.File.Fileid, "div "+where, "FILEID does not match any fileSec file")
+      }
+    }
+    for _, c := range div.Children {
+      walk(c, where)
+    }
+  }
+  for _, sm := range mets.StructMap {
+    walk(sm.Parent, "structMap "+sm.Label)
+  }
+
+  for _, a := range mets.AdminSec {
+    used := admidUsed[a.ID]
+    for _, id := range amdChildIds(a) {
+      used = used || admidUsed[id]
+    }
+    if !used {
+      v.problem(ProblemOrphanedAmdSec, a.ID, "amdSec", "amdSec is not referenced by any file or div")
+    }
+  }
+  for _, d := range mets.DescriptiveSec {
+    if !dmdidUsed[d.ID] {
+      v.problem(ProblemOrphanedDmdSec, d.ID, "dmdSec", "dmdSec is not referenced by any div")
+    }
+  }
+  for _, grp := range mets.FileSec.FileGrp {
+    for _, file := range grp.Files {
+      if !fileidUsed[file.ID] {
+        v.problem(ProblemFileNotInStructMap, file.ID, "fileGrp "+grp.FileType, "file has no fptr in any structMap")
+      }
+    }
+  }
+
+  return IntegrityReport{Valid: len(v.problems) == 0, Problems: v.problems}
+}
+
+// collects declared IDs and the problems found
+type validator struct {
+  ids      map[string]string // ID -> element that declared it
+  problems []IntegrityProblem
+}
+
+// record an ID, reporting it if it was already declared
+func (v *validator) declare(id string, element string) {
+  if id == "" {
+    return
+  }
+  first, ok := v.ids[id]
+  if ok {
+    v.problem(ProblemDuplicateID, id, element, fmt.Sprintf("ID already used by %s", first))
+    return
+  }
+  v.ids[id] = element
+}
+
+func (v *validator) problem(kind string, id string, element string, message string) {
+  v.problems = append(v.problems, IntegrityProblem{Kind: kind, ID: id, Element: element, Message: message})
+}
+
+// IDs of the metadata sections inside an amdSec
+func amdChildIds(a AdminSec) []string {
+  var ids []string
+  if a.TechnicalMD.ID != "" {
+    ids = append(ids, a.TechnicalMD.ID)
+  }
+  if a.SourceMD.ID != "" {
+    ids = append(ids, a.SourceMD.ID)
+  }
+  for _, r := range a.RightsMD {
+    if r.ID != "" {
+      ids = append(ids, r.ID)
+    }
+  }
+  for _, d := range a.DigiProvMD {
+    if d.ID != "" {
+      ids = append(ids, d.ID)
+    }
+  }
+  return ids
+}
+
+// split an IDREFS attribute, DMDID="dmdSec_3 dmdSec_4"
+func splitIds(ids string) []string {
+  return strings.Fields(ids)
+}
@@ -0,0 +1,54 @@
+package metsparser
+
+import (
+  "os"
+  "reflect"
+  "testing"
+)
+
+func parseFixture(t *testing.T, name string) *Mets {
+  f, err := os.Open("testdata/" + name)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer f.Close()
+  mets, err := Parse(f)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return mets
+}
+
+func TestValidate(t *testing.T) {
+  report := Validate(parseFixture(t, "METS.abc.xml"))
+  if !report.Valid || len(report.Problems) > 0 {
+    t.Errorf("METS.abc.xml: got problems %+v", report.Problems)
+  }
+
+  report = Validate(parseFixture(t, "METS.broken-references.xml"))
+  if report.Valid {
+    t.Error("METS.broken-references.xml: reported valid")
+  }
+  type problem struct {
+    kind    string
+    id      string
+    element string
+  }
+  var got []problem
+  for _, p := range report.Problems {
+    got = append(got, problem{p.Kind, p.ID, p.Element})
+  }
+  want := []problem{
+    {ProblemDuplicateID, "dmdSec_1", "fileSec"},
+    {ProblemDanglingAdmid, "amdSec_missing", "file file-2"},
+    {ProblemDanglingDmdid, "dmdSec_missing", "div structMap Archivematica default/pkg/objects/a.txt"},
+    {ProblemDanglingAdmid, "techMD_missing", "div structMap Archivematica default/pkg/objects/b.txt"},
+    {ProblemDanglingFileid, "file-missing", "div structMap Archivematica default/pkg/objects/d.txt"},
+    {ProblemOrphanedAmdSec, "amdSec_orphan", "amdSec"},
+    {ProblemOrphanedDmdSec, "dmdSec_orphan", "dmdSec"},
+    {ProblemFileNotInStructMap, "dmdSec_1", "fileGrp original"},
+  }
+  if !reflect.DeepEqual(got, want) {
+    t.Errorf("got %+v\nwant %+v", got, want)
+  }
+}
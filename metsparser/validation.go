@@ -0,0 +1,78 @@
+package metsparser
+
+import (
+  "strings"
+)
+
+// validation results of the validation events of a file, in event order
+func validationResults(events []Events) []ValidationResult {
+  var results []ValidationResult
+  for _, e := range events {
+    if !strings.Contains(strings.ToLower(e.Type), "validation") {
+      continue
+    }
+    results = append(results, validationResult(e))
+  }
+  return results
+}
+
+// read the tool from the event detail and the outcome from its note, as
+// Archivematica writes them for JHOVE:
+//   program="JHOVE"; version="1.20.1"
+//   format="PDF"; version="1.4"; result="Well-Formed, but not valid"
+func validationResult(e Events) ValidationResult {
+  result := ValidationResult{EventUuid: e.Uuid, Outcome: e.Outcome, Messages: []string{}}
+  if e.Tool != nil {
+    result.Tool = e.Tool.Program
+    result.ToolVersion = e.Tool.Version
+  }
+
+  note, text := parseDetail(e.DetailNote)
+  result.Format = note["format"]
+  result.FormatVersion = note["version"]
+  result.Result = note["result"]
+  for _, key := range []string{"status", "message", "messages", "error", "errors"} {
+    if note[key] != "" {
+      result.Messages = append(result.Messages, note[key])
+    }
+  }
+  result.Messages = append(result.Messages, text...)
+
+  result.WellFormed, result.Valid = wellFormedValid(result.Result)
+  if result.Valid == nil {
+    // no JHOVE result, go by the outcome
+    switch outcomeSeverity(e.Outcome) {
+    case OutcomeFailed:
+      result.Valid = boolPtr(false)
+    case "":
+      if e.Outcome != "" {
+        result.Valid = boolPtr(true)
+      }
+    }
+  }
+  return result
+}
+
+// well-formed and valid flags of a JHOVE result, nil when it doesn't say
+func wellFormedValid(result string) (*bool, *bool) {
+  r := strings.ToLower(result)
+  var wellFormed, valid *bool
+  switch {
+  case strings.Contains(r, "not well-formed") || strings.Contains(r, "not well formed"):
+    wellFormed, valid = boolPtr(false), boolPtr(false)
+  case strings.Contains(r, "well-formed") || strings.Contains(r, "well formed"):
+    wellFormed = boolPtr(true)
+  }
+  switch {
+  case valid != nil:
+  case strings.Contains(r, "not valid") || strings.Contains(r, "invalid"):
+    valid = boolPtr(false)
+  case strings.Contains(r, "valid"):
+    valid = boolPtr(true)
+  }
+  return wellFormed, valid
+}
+
+func boolPtr(b bool) *bool {
+  return &b
+}
@@ -0,0 +1,101 @@
+package metsparser
+
+import (
+  "reflect"
+  "testing"
+)
+
+// nil, false or true for a flag
+func flag(b *bool) string {
+  if b == nil {
+    return "nil"
+  }
+  if *b {
+    return "true"
+  }
+  return "false"
+}
+
+func TestWellFormedValid(t *testing.T) {
+  tests := []struct {
+    result     string
+    wellFormed string
+    valid      string
+  }{
+    // JHOVE
+    {"Well-Formed and valid", "true", "true"},
+    {"Well-Formed, but not valid", "true", "false"},
+    {"Not well-formed", "false", "false"},
+    {"well formed", "true", "nil"},
+    {"not well formed", "false", "false"},
+    {"Invalid", "nil", "false"},
+    {"", "nil", "nil"},
+    {"Unknown", "nil", "nil"},
+  }
+  for _, tt := range tests {
+    wellFormed, valid := wellFormedValid(tt.result)
+    if flag(wellFormed) != tt.wellFormed || flag(valid) != tt.valid {
+      t.Errorf("%q: well-formed %s, valid %s, want %s, %s", tt.result, flag(wellFormed), flag(valid), tt.wellFormed, tt.valid)
+    }
+  }
+}
+
+func TestValidationResult(t *testing.T) {
+  jhove := &EventTool{Program: "JHOVE", Version: "1.20.1"}
+  tests := []struct {
+    name       string
+    event      Events
+    result     string
+    wellFormed string
+    valid      string
+  }{
+    {"well-formed and valid", Events{Tool: jhove, Outcome: "pass", DetailNote: `format="PDF"; version="1.4"; result="Well-Formed and valid"`},
+      "Well-Formed and valid", "true", "true"},
+    {"well-formed, not valid", Events{Tool: jhove, Outcome: "fail", DetailNote: `format="PDF"; version="1.4"; result="Well-Formed, but not valid"`},
+      "Well-Formed, but not valid", "true", "false"},
+    {"not well-formed", Events{Tool: jhove, Outcome: "fail", DetailNote: `format="PDF"; version="1.4"; result="Not well-formed"`},
+      "Not well-formed", "false", "false"},
+    // no JHOVE result, the outcome decides validity alone
+    {"failed without result", Events{Outcome: "Fail"}, "", "nil", "false"},
+    {"passed without result", Events{Outcome: "Pass"}, "", "nil", "true"},
+    {"no outcome", Events{}, "", "nil", "nil"},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      tt.event.Uuid = "event-1"
+      r := validationResult(tt.event)
+      if r.Result != tt.result || flag(r.WellFormed) != tt.wellFormed || flag(r.Valid) != tt.valid {
+        t.Errorf("result %q, well-formed %s, valid %s, want %q, %s, %s", r.Result, flag(r.WellFormed), flag(r.Valid), tt.result, tt.wellFormed, tt.valid)
+      }
+      if r.EventUuid != "event-1" || r.Outcome != tt.event.Outcome || r.Messages == nil {
+        t.Errorf("event %q, outcome %q, messages %v", r.EventUuid, r.Outcome, r.Messages)
+      }
+      if tt.event.Tool != nil && (r.Tool != "JHOVE" || r.ToolVersion != "1.20.1" || r.Format != "PDF" || r.FormatVersion != "1.4") {
+        t.Errorf("tool %s %s, format %s %s", r.Tool, r.ToolVersion, r.Format, r.FormatVersion)
+      }
+    })
+  }
+}
+
+func TestValidationResultMessages(t *testing.T) {
+  r := validationResult(Events{DetailNote: `result="Not well-formed"; status="Invalid page tree"; Lexical error at offset 2048`})
+  if !reflect.DeepEqual(r.Messages, []string{"Invalid page tree", "Lexical error at offset 2048"}) {
+    t.Errorf("messages %q", r.Messages)
+  }
+}
+
+// only validation events give results, in event order
+func TestValidationResults(t *testing.T) {
+  events := []Events{
+    {Uuid: "1", Type: "validation", Outcome: "pass"},
+    {Uuid: "2", Type: "virus check", Outcome: "Pass"},
+    {Uuid: "3", Type: "Validation", Outcome: "fail"},
+  }
+  var uuids []string
+  for _, r := range validationResults(events) {
+    uuids = append(uuids, r.EventUuid)
+  }
+  if !reflect.DeepEqual(uuids, []string{"1", "3"}) {
+    t.Errorf("results for events %v, want 1 and 3", uuids)
+  }
+}
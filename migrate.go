@@ -0,0 +1,110 @@
+package main
+
+import (
+  "flag"
+  "io/ioutil"
+  "log"
+  "os"
+  "path/filepath"
+  "strings"
+
+  "github.com/msarmie/canopus-mets-parser/metsparser"
+)
+
+// Outcome of migrating one manifest file
+type migrateResult struct {
+  File   string                     `json:"file"`
+  Output string                     `json:"output,omitempty"`
+  Result metsparser.MigrationResult `json:"result"`
+  Error  string                     `json:"error,omitempty"`
+}
+
+// migrate subcommand: upgrade manifest files, or directories of them, to a
+// newer schema version; exit status 1 if any failed or lost fields, fields
+// only filled with placeholders don't count
+func runMigrate(args []string) int {
+  flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+  targetUserInput := flags.String("to", metsparser.SchemaVersion, "Schema version to migrate to")
+  outputDirPathUserInput := flags.String("o", "", "Write migrated manifests to this directory")
+  inPlaceUserInput := flags.Bool("in-place", false, "Overwrite the manifests with their migrated version")
+  reportFileUserInput := flags.String("report", "", "Write the report to a file instead of stdout")
+  flags.Parse(args)
+
+  if *outputDirPathUserInput == "" && !*inPlaceUserInput {
+    log.Print("ERROR : MUST GIVE AN OUTPUT DIRECTORY (-o) OR -in-place")
+    return 2
+  }
+
+  var files []string
+  for _, arg := range flags.Args() {
+    found, err := findManifestFiles(arg)
+    if err != nil {
+      log.Print(err)
+      return 2
+    }
+    files = append(files, found...)
+  }
+
+  ok := true
+  results := []migrateResult{}
+  for _, file := range files {
+    result := migrateResult{File: file}
+    output, err := migrateManifestFile(file, *targetUserInput, *outputDirPathUserInput, &result.Result)
+    if err != nil {
+      result.Error = err.Error()
+    }
+    result.Output = output
+    ok = ok && err == nil && len(result.Result.NotCarried) == 0
+    results = append(results, result)
+  }
+
+  err := writeReport(*reportFileUserInput, &results)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+  if !ok {
+    return 1
+  }
+  return 0
+}
+
+// migrate one manifest, returning where it was written
+func migrateManifestFile(file string, target string, outputDir string, result *metsparser.MigrationResult) (string, error) {
+  data, err := ioutil.ReadFile(file)
+  if err != nil {
+    return "", err
+  }
+  output, migration, err := metsparser.MigrateJSON(data, target)
+  *result = migration
+  if err != nil {
+    return "", err
+  }
+  destination := file
+  if outputDir != "" {
+    destination = filepath.Join(outputDir, filepath.Base(file))
+  }
+  return destination, ioutil.WriteFile(destination, output, 0750)
+}
+
+// a manifest file, or every *_metadata.json under a directory
+func findManifestFiles(path string) ([]string, error) {
+  info, err := os.Stat(path)
+  if err != nil {
+    return nil, err
+  }
+  if !info.IsDir() {
+    return []string{path}, nil
+  }
+  var files []string
+  err = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+    if err != nil {
+      return err
+    }
+    if !info.IsDir() && strings.HasSuffix(info.Name(), "_metadata.json") {
+      files = append(files, path)
+    }
+    return nil
+  })
+  return files, err
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+  "encoding/json"
+  "flag"
+  "fmt"
+  "io/ioutil"
+  "log"
+  "path/filepath"
+
+  "github.com/msarmie/canopus-mets-parser/metsparser"
+)
+
+// schema subcommand: print the JSON Schema of the manifest, or write the
+// current one next to the published schemas
+func runSchema(args []string) int {
+  flags := flag.NewFlagSet("schema", flag.ExitOnError)
+  versionUserInput := flags.String("version", "", "Print the published schema of this manifest version instead of generating the current one")
+  outputDirPathUserInput := flags.String("o", "", "Write the generated schema as manifest-<version>.json in this directory")
+  flags.Parse(args)
+
+  var output []byte
+  var err error
+  if *versionUserInput != "" {
+    output, err = metsparser.Schema(*versionUserInput)
+  } else {
+    output, err = json.MarshalIndent(metsparser.GenerateSchema(), "", "  ")
+    output = append(output, '\n')
+  }
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+
+  if *outputDirPathUserInput != "" {
+    target := filepath.Join(*outputDirPathUserInput, "manifest-"+metsparser.SchemaVersion+".json")
+    err = ioutil.WriteFile(target, output, 0644)
+    if err != nil {
+      log.Print(err)
+      return 2
+    }
+    return 0
+  }
+  fmt.Print(string(output))
+  return 0
+}
+
+// Outcome of checking one manifest against a schema
+type checkResult struct {
+  File          string   `json:"file"`
+  SchemaVersion string   `json:"schema_version"`
+  Valid         bool     `json:"valid"`
+  Violations    []string `json:"violations"`
+  Error         string   `json:"error,omitempty"`
+}
+
+// check-json subcommand: validate existing manifest files, exit status 1 if any is invalid
+func runCheckJSON(args []string) int {
+  flags := flag.NewFlagSet("check-json", flag.ExitOnError)
+  versionUserInput := flags.String("schema-version", "", "Validate against this manifest version (default: each file's own schema_version)")
+  schemaFileUserInput := flags.String("schema", "", "Validate against a schema file instead of a published version")
+  reportFileUserInput := flags.String("report", "", "Write the report to a file instead of stdout")
+  flags.Parse(args)
+
+  var schema []byte
+  if *schemaFileUserInput != "" {
+    data, err := ioutil.ReadFile(*schemaFileUserInput)
+    if err != nil {
+      log.Print(err)
+      return 2
+    }
+    schema = data
+  }
+
+  valid := true
+  results := []checkResult{}
+  for _, file := range flags.Args() {
+    result := checkManifestFile(file, schema, *versionUserInput)
+    valid = valid && result.Valid
+    results = append(results, result)
+  }
+
+  err := writeReport(*reportFileUserInput, &results)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+  if !valid {
+    return 1
+  }
+  return 0
+}
+
+// validate a manifest file against schema, or the published schema of version,
+// or of the file's own schema_version
+func checkManifestFile(file string, schema []byte, version string) checkResult {
+  result := checkResult{File: file, Violations: []string{}}
+  data, err := ioutil.ReadFile(file)
+  if err != nil {
+    result.Error = err.Error()
+    return result
+  }
+  if schema == nil {
+    if version == "" {
+      declared := struct {
+        SchemaVersion string `json:"schema_version"`
+      }{}
+      err = json.Unmarshal(data, &declared)
+      if err != nil {
+        result.Error = err.Error()
+        return result
+      }
+      version = declared.SchemaVersion
+    }
+    result.SchemaVersion = version
+    schema, err = metsparser.Schema(version)
+    if err != nil {
+      result.Error = err.Error()
+      return result
+    }
+  }
+
+  violations, err := metsparser.ValidateJSON(schema, data)
+  if err != nil {
+    result.Error = err.Error()
+    return result
+  }
+  result.Violations = violations
+  result.Valid = len(violations) == 0
+  return result
+}
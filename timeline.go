@@ -0,0 +1,82 @@
+package main
+
+import (
+  "encoding/json"
+  "flag"
+  "io/ioutil"
+  "log"
+  "strings"
+
+  "github.com/msarmie/canopus-mets-parser/metsparser"
+)
+
+// timeline subcommand: report the events of each package in time order with
+// ingest durations, from METS files, packed AIPs or manifests already written
+func runTimeline(args []string) int {
+  flags := flag.NewFlagSet("timeline", flag.ExitOnError)
+  reportFileUserInput := flags.String("report", "", "Write the report to a file instead of stdout")
+  manifestFlags := addManifestFlags(flags)
+  flags.Parse(args)
+
+  opts, err := manifestFlags.options()
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+
+  reports := []metsparser.TimelineReport{}
+  for _, path := range flags.Args() {
+    manifestObject, err := loadManifest(path, opts)
+    if err != nil {
+      log.Printf("%s: %v", path, err)
+      return 2
+    }
+    reports = append(reports, metsparser.Timeline(manifestObject))
+  }
+
+  err = writeReport(*reportFileUserInput, &reports)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+  return 0
+}
+
+// read a manifest written earlier, or build it from a METS file or packed AIP
+func loadManifest(path string, opts metsparser.Options) (*metsparser.ObjectMetsManifest, error) {
+  if strings.HasSuffix(path, ".json") {
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+      return nil, err
+    }
+    // older manifests are upgraded first, so their new fields are derived
+    // from what they hold rather than left empty
+    var header struct {
+      SchemaVersion string `json:"schema_version"`
+    }
+    json.Unmarshal(data, &header)
+    if header.SchemaVersion != "" && header.SchemaVersion != metsparser.SchemaVersion {
+      data, _, err = metsparser.MigrateJSON(data, metsparser.SchemaVersion)
+      if err != nil {
+        return nil, err
+      }
+    }
+    manifestObject := metsparser.ObjectMetsManifest{}
+    err = json.Unmarshal(data, &manifestObject)
+    if err != nil {
+      return nil, err
+    }
+    return &manifestObject, nil
+  }
+
+  file, err := openMets(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  mets, err := metsparser.Parse(file)
+  if err != nil {
+    return nil, err
+  }
+  return metsparser.BuildManifest(mets, opts)
+}
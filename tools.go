@@ -0,0 +1,51 @@
+package main
+
+import (
+  "flag"
+  "log"
+
+  "github.com/msarmie/canopus-mets-parser/metsparser"
+)
+
+// tools subcommand: inventory of the tools and versions that processed the
+// files of many packages, from METS files, packed AIPs or manifests
+func runTools(args []string) int {
+  flags := flag.NewFlagSet("tools", flag.ExitOnError)
+  batchDirUserInput := flags.String("batch", "", "Also read every METS.<uuid>.xml and packed AIP found under a directory")
+  reportFileUserInput := flags.String("report", "", "Write the report to a file instead of stdout")
+  manifestFlags := addManifestFlags(flags)
+  flags.Parse(args)
+
+  opts, err := manifestFlags.options()
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+
+  paths := flags.Args()
+  if *batchDirUserInput != "" {
+    found, err := findMetsFiles(*batchDirUserInput)
+    if err != nil {
+      log.Print(err)
+      return 2
+    }
+    paths = append(paths, found...)
+  }
+
+  // only the merged tools outlive each loop, however many packages are read
+  report := metsparser.ToolReport{Packages: []string{}, Tools: []metsparser.ToolUsage{}}
+  for _, path := range paths {
+    manifestObject, err := loadManifest(path, opts)
+    if err != nil {
+      log.Printf("%s: %v", path, err)
+      return 2
+    }
+    report.Add(manifestObject)
+  }
+  err = writeReport(*reportFileUserInput, &report)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+  return 0
+}
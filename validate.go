@@ -0,0 +1,45 @@
+package main
+
+import (
+  "flag"
+  "log"
+
+  "github.com/msarmie/canopus-mets-parser/metsparser"
+)
+
+// validate subcommand: report broken ID references, exit status 1 if there are any
+func runValidate(args []string) int {
+  flags := flag.NewFlagSet("validate", flag.ExitOnError)
+  metsFilePathUserInput := flags.String("mets", "", "Provide a mets filepath")
+  reportFileUserInput := flags.String("report", "", "Write the report to a file instead of stdout")
+  flags.Parse(args)
+
+  if *metsFilePathUserInput == "" {
+    log.Print("ERROR : MUST ENTER A METS FILEPATH")
+    return 2
+  }
+
+  file, err := openMets(*metsFilePathUserInput)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+  defer file.Close()
+
+  mets, err := metsparser.Parse(file)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+
+  report := metsparser.Validate(mets)
+  err = writeReport(*reportFileUserInput, &report)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+  if !report.Valid {
+    return 1
+  }
+  return 0
+}
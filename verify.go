@@ -0,0 +1,91 @@
+package main
+
+import (
+  "flag"
+  "log"
+  "path/filepath"
+  "time"
+
+  "github.com/msarmie/canopus-mets-parser/metsparser"
+)
+
+// verify subcommand: check the payload of an extracted AIP against the METS
+// fixity, exit status 1 if anything is missing, extra or mismatched
+func runVerify(args []string) int {
+  flags := flag.NewFlagSet("verify", flag.ExitOnError)
+  metsFilePathUserInput := flags.String("mets", "", "Provide a mets filepath")
+  payloadDirUserInput := flags.String("dir", "", "Extracted AIP directory the METS file locations are relative to (default: the METS directory, required for a packed AIP)")
+  reportFileUserInput := flags.String("report", "", "Write the report to a file instead of stdout")
+  outputDirPathUserInput := flags.String("out", "", "Also write the manifest, with a fixity check event per file, to this directory")
+  manifestFlags := addManifestFlags(flags)
+  flags.Parse(args)
+
+  filePath := *metsFilePathUserInput
+  if filePath == "" {
+    log.Print("ERROR : MUST ENTER A METS FILEPATH")
+    return 2
+  }
+  dir := *payloadDirUserInput
+  if dir == "" {
+    // the payload of a packed AIP is still inside the archive
+    if metsparser.IsPackedAIP(filePath) {
+      log.Print("ERROR : MUST GIVE THE EXTRACTED PAYLOAD DIRECTORY (-dir) TO VERIFY A PACKED AIP")
+      return 2
+    }
+    dir = filepath.Dir(filePath)
+  }
+
+  file, err := openMets(filePath)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+  defer file.Close()
+
+  mets, err := metsparser.Parse(file)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+
+  report, err := metsparser.Verify(mets, dir)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+
+  if *outputDirPathUserInput != "" {
+    opts, err := manifestFlags.options()
+    if err != nil {
+      log.Print(err)
+      return 2
+    }
+    manifestObject, err := metsparser.BuildManifest(mets, opts)
+    if err != nil {
+      log.Print(err)
+      return 2
+    }
+    // a failed critical check leaves the manifest unwritten, the report says why
+    err = metsparser.RecordFixityCheck(manifestObject, report, time.Now(), opts.CriticalEvents)
+    if err != nil {
+      log.Print(err)
+    } else {
+      target := *outputDirPathUserInput + "/" + manifestFileName(manifestObject, filePath)
+      err = writeNewStructToFile(target, manifestObject)
+      if err != nil {
+        log.Print(err)
+        return 2
+      }
+    }
+  }
+
+  err = writeReport(*reportFileUserInput, &report)
+  if err != nil {
+    log.Print(err)
+    return 2
+  }
+  if !report.Valid {
+    return 1
+  }
+  return 0
+}